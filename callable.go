@@ -8,13 +8,42 @@ import (
 
 // callable represents a handler that just specifies inputs and outputs
 type callable struct {
-	fnt  reflect.Type
-	fnv  reflect.Value
-	inpt reflect.Type
+	fnt   reflect.Type
+	fnv   reflect.Value
+	inpts []reflect.Type
+
+	decodedInpt reflect.Type // the one input that doesn't implement SkipDecode
+	args        []callArg
+
+	unrenderable []reflect.Type // result types that no encoder can render
+}
+
+// argKind determines how an argument of the callable is provided
+type argKind uint8
+
+const (
+	inputArg argKind = iota
+	contextArg
+	requestArg
+	responseArg
+	providedArg
+)
+
+// callArg describes an argument of the callable
+type callArg struct {
+	kind  argKind
+	input int       // index of the input, for input arguments
+	prov  *provider // for provided arguments
 }
 
-// newCallable reflects on the provided function 'f' to create the callable
-func newCallable(f interface{}) (c *callable, err error) {
+// newCallable reflects on the provided function 'f' to create the callable.
+// Arguments can be a context, the *http.Request, a ResponseWriter, any type
+// that is provided by one of the providers or else an input that is bound
+// from the request. Only one input can be decoded from the body, additional
+// inputs must opt in by implementing SkipDecode such that they are bound by
+// request hooks only, any other argument must be provided. Results can be
+// anything that can be rendered, with at most one error.
+func newCallable(f interface{}, provs ...*provider) (c *callable, err error) {
 	var op Op = "newCallable"
 
 	c = &callable{fnv: reflect.ValueOf(f)}
@@ -23,67 +52,108 @@ func newCallable(f interface{}) (c *callable, err error) {
 		return nil, Err(op, "argument is not a function")
 	}
 
-	switch c.fnt.NumIn() {
-	case 0:
-	case 1:
-		if canBeAssignedContext(c.fnt.In(0)) {
-			break
+	c.args = make([]callArg, c.fnt.NumIn())
+	for i := range c.args {
+		at := c.fnt.In(i)
+		switch {
+		case canBeAssignedContext(at):
+			c.args[i].kind = contextArg
+		case at == reqTyp:
+			c.args[i].kind = requestArg
+		case at == resTyp || at == httpResTyp:
+			c.args[i].kind = responseArg
+		case findProvider(provs, at) != nil:
+			c.args[i] = callArg{kind: providedArg, prov: findProvider(provs, at)}
+		case canSkipDecode(at):
+			c.args[i] = callArg{kind: inputArg, input: len(c.inpts)}
+			c.inpts = append(c.inpts, at)
+		case c.decodedInpt != nil:
+			return nil, Err(op, "function can only have one input argument, '"+
+				at.String()+"' is not provided and '"+c.decodedInpt.String()+"' is already the input")
+		default:
+			c.args[i] = callArg{kind: inputArg, input: len(c.inpts)}
+			c.inpts = append(c.inpts, at)
+			c.decodedInpt = at
 		}
+	}
 
-		c.inpt = c.fnt.In(0)
-	case 2:
-		if !canBeAssignedContext(c.fnt.In(0)) {
-			return nil, Err(op, "function's first argument must implement context.Context")
+	var nerrs int
+	for i := 0; i < c.fnt.NumOut(); i++ {
+		switch ot := c.fnt.Out(i); {
+		case ot.Implements(errTyp):
+			nerrs++
+		case ot.Kind() == reflect.Func, ot.Kind() == reflect.Chan, ot.Kind() == reflect.UnsafePointer:
+			c.unrenderable = append(c.unrenderable, ot)
 		}
+	}
 
-		c.inpt = c.fnt.In(1)
-	default:
-		return nil, Err(op, "function must have at most 2 arguments")
+	if nerrs > 1 {
+		return nil, Err(op, "function can return at most one error")
 	}
 
 	return
 }
 
-// Input returns the input argument value such that it can be used to bind
-func (c *callable) Input() reflect.Value {
-	if c.inpt == nil {
-		return reflect.ValueOf(nil)
-	}
+// Inputs returns the input argument values such that they can be used to bind
+func (c *callable) Inputs() []reflect.Value {
+	ins := make([]reflect.Value, len(c.inpts))
+	for i, inpt := range c.inpts {
+		if inpt.Kind() == reflect.Ptr {
+			ins[i] = reflect.New(inpt.Elem())
+			continue
+		}
 
-	if c.inpt.Kind() == reflect.Ptr {
-		return reflect.New(c.inpt.Elem())
+		ins[i] = reflect.New(inpt)
 	}
 
-	return reflect.New(c.inpt)
+	return ins
 }
 
 // inArg returns the input argument based on its type
-func (c *callable) inArg(in reflect.Value) reflect.Value {
-	if c.inpt.Kind() == reflect.Ptr {
+func (c *callable) inArg(i int, in reflect.Value) reflect.Value {
+	if c.inpts[i].Kind() == reflect.Ptr {
 		return in
 	}
 
 	return in.Elem()
 }
 
-// Setup the arguments for calling the callable
-func (c *callable) Args(r *http.Request, in reflect.Value) []reflect.Value {
-	args := make([]reflect.Value, c.fnt.NumIn())
-	switch len(args) {
-	case 0:
-	case 1:
-		if in == reflect.ValueOf(nil) {
-			args[0] = reflect.ValueOf(r.Context())
-			break
+// Args sets up the arguments for calling the callable. Providers are called
+// in the order of the arguments and the cleanups of providers that were
+// called are returned, even if a later provider failed.
+func (c *callable) Args(w ResponseWriter, r *http.Request, ins []reflect.Value) (args []reflect.Value, cleanups []func(), err error) {
+	const op Op = "callable.Args"
+
+	args = make([]reflect.Value, len(c.args))
+	for i, arg := range c.args {
+		switch arg.kind {
+		case contextArg:
+			args[i] = reflect.ValueOf(r.Context())
+		case requestArg:
+			args[i] = reflect.ValueOf(r)
+		case responseArg:
+			args[i] = reflect.ValueOf(w)
+		case inputArg:
+			args[i] = c.inArg(arg.input, ins[arg.input])
+		case providedArg:
+			var cleanup func()
+			args[i], cleanup, err = arg.prov.provide(r)
+			if err != nil {
+				at := c.fnt.In(i).String()
+				if Kind(err) == OtherError {
+					return nil, cleanups, Err(op, "failed to provide '"+at+"'", err, ServerError)
+				}
+
+				return nil, cleanups, Err(op, "failed to provide '"+at+"'", err)
+			}
+
+			if cleanup != nil {
+				cleanups = append(cleanups, cleanup)
+			}
 		}
-
-		args[0] = c.inArg(in)
-	case 2:
-		args[0] = reflect.ValueOf(r.Context())
-		args[1] = c.inArg(in)
 	}
 
-	return args
+	return args, cleanups, nil
 }
 
 // Call the callable with arguments and return its outputs
@@ -98,8 +168,24 @@ func (c *callable) Call(args []reflect.Value) []interface{} {
 	return result
 }
 
-// keep the ctx type, this is the idiom to get it: https://godoc.org/reflect#example-TypeOf
-var ctxTyp = reflect.TypeOf((*context.Context)(nil)).Elem()
+var (
+	// keep the ctx type, this is the idiom to get it: https://godoc.org/reflect#example-TypeOf
+	ctxTyp = reflect.TypeOf((*context.Context)(nil)).Elem()
+
+	resTyp     = reflect.TypeOf((*ResponseWriter)(nil)).Elem()
+	httpResTyp = reflect.TypeOf((*http.ResponseWriter)(nil)).Elem()
+)
+
+var skipDecodeTyp = reflect.TypeOf((*interface{ SkipDecode() bool })(nil)).Elem()
+
+// returns whether the input type (or a pointer to it) has a SkipDecode method
+func canSkipDecode(typ reflect.Type) bool {
+	if typ.Kind() == reflect.Interface {
+		return false
+	}
+
+	return typ.Implements(skipDecodeTyp) || reflect.PointerTo(typ).Implements(skipDecodeTyp)
+}
 
 // returns whether the type implements the standard lib context interface
 func canBeAssignedContext(typ reflect.Type) bool {
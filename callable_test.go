@@ -22,10 +22,13 @@ func TestNewCallable(t *testing.T) {
 		{func() {}, nil, nil},
 		{func(string) {}, nil, reflect.TypeOf("")},
 		{func(context.Context) {}, nil, nil},
-		{func(u, v string) {}, Err(Op("newCallable"), "function's first argument must implement context.Context"), nil},
+		{func(u, v string) {}, Err(Op("newCallable"), "function can only have one input argument, 'string' is not provided and 'string' is already the input"), nil},
+		{func(struct{ A string }, *struct{ B string }) {}, Err(Op("newCallable"), "function can only have one input argument, '*struct { B string }' is not provided and 'struct { A string }' is already the input"), nil},
+		{func(headerInput, struct{ A string }, *headerInput) {}, nil, reflect.TypeOf(headerInput{})},
+		{func() (error, error) { return nil, nil }, Err(Op("newCallable"), "function can return at most one error"), nil},
 		{func(myCtx, string) {}, nil, reflect.TypeOf("")},
 		{func(context.Context, *string) {}, nil, reflect.TypeOf((*string)(nil))},
-		{func(u, v, w string) {}, Err(Op("newCallable"), "function must have at most 2 arguments"), nil},
+		{func(string, context.Context) {}, nil, reflect.TypeOf("")},
 	} {
 		t.Run(strconv.Itoa(i), func(t *testing.T) {
 			call, err := newCallable(c.fn)
@@ -37,8 +40,12 @@ func TestNewCallable(t *testing.T) {
 				return
 			}
 
-			if call.inpt != c.expTyp {
-				t.Fatalf("expected '%v', got: '%v'", c.expTyp, call.inpt.Kind())
+			if c.expTyp == nil && len(call.inpts) > 0 {
+				t.Fatalf("expected no input, got: '%v'", call.inpts)
+			}
+
+			if c.expTyp != nil && call.inpts[0] != c.expTyp {
+				t.Fatalf("expected '%v', got: '%v'", c.expTyp, call.inpts[0])
 			}
 		})
 	}
@@ -71,12 +78,12 @@ func TestCallableInput(t *testing.T) {
 				t.Fatalf("unexpected, got: %v", err)
 			}
 
-			inp := call.Input()
-			if inp == reflect.ValueOf(nil) && c.expZero {
+			ins := call.Inputs()
+			if len(ins) == 0 && c.expZero {
 				return
 			}
 
-			in := inp.Interface()
+			in := ins[0].Interface()
 
 			dec := json.NewDecoder(strings.NewReader(c.buf))
 			err = dec.Decode(in)
@@ -110,9 +117,12 @@ func TestCallableArgs(t *testing.T) {
 				t.Fatalf("unexpected, got: %v", err)
 			}
 
-			inp := call.Input()
+			ins := call.Inputs()
 			req := httptest.NewRequest("GET", "/", nil)
-			args := call.Args(req, inp)
+			args, _, err := call.Args(nil, req, ins)
+			if err != nil {
+				t.Fatalf("unexpected, got: %v", err)
+			}
 
 			if fmt.Sprint(c.expArgs) != fmt.Sprint(args) {
 				t.Fatalf("expected %v, got: %v", c.expArgs, args)
@@ -144,18 +154,21 @@ func TestCallableCall(t *testing.T) {
 				t.Fatalf("unexpected, got: %v", err)
 			}
 
-			inp := call.Input()
+			ins := call.Inputs()
 
 			if c.buf != "" {
 				dec := json.NewDecoder(strings.NewReader(c.buf))
-				err = dec.Decode(inp.Interface())
+				err = dec.Decode(ins[0].Interface())
 				if err != nil {
 					t.Fatalf("unexpected, got: %v", err)
 				}
 			}
 
 			req := httptest.NewRequest("GET", "/", nil)
-			args := call.Args(req, inp)
+			args, _, err := call.Args(nil, req, ins)
+			if err != nil {
+				t.Fatalf("unexpected, got: %v", err)
+			}
 
 			outs := call.Call(args)
 			if !reflect.DeepEqual(outs, c.expOuts) {
@@ -1,8 +1,11 @@
 package ep
 
 import (
+	"log/slog"
 	"net/http"
 	"reflect"
+	"slices"
+	"time"
 
 	"github.com/advanderveer/ep/epcoding"
 )
@@ -10,12 +13,29 @@ import (
 // Codec provides http.Handlers that automatically decode requests and encode
 // responses based on input and output structs
 type Codec struct {
-	resHooks []ResponseHook
-	reqHooks []RequestHook
-	errHooks []ErrorHook
+	resHooks     []ResponseHook
+	resPhases    []HookPhase
+	reqHooks     []RequestHook
+	decodedHooks []RequestHook
+	errHooks     []RequestErrorHook
+	panicHooks   []PanicHook
+	afterHooks   []AfterHook
+	providers    []*provider
 
 	decodings []epcoding.Decoding
 	encodings []epcoding.Encoding
+
+	cors          *cors
+	requestID     *requestID
+	tracer        Tracer
+	bufferOutputs bool
+	debug         bool
+	serverTiming  bool
+	hookConflicts HookConflictMode
+	logs          *slog.Logger
+
+	middleware     []func(http.Handler) http.Handler
+	callMiddleware []CallMiddleware
 }
 
 // New initiates a new ep Codec
@@ -25,35 +45,219 @@ func New(opts ...Option) (c *Codec) {
 	return
 }
 
+// With returns a new Codec that inherits the configuration of this Codec and
+// applies the options on top of it. Hooks and encodings are added after the
+// inherited ones, the Clear options can be used to remove them. The parent
+// Codec is not affected.
+func (c *Codec) With(opts ...Option) *Codec {
+	child := *c
+
+	// clipping the capacity makes sure that appending options always
+	// reallocate instead of writing into the parent's backing arrays.
+	child.resHooks = slices.Clip(c.resHooks)
+	child.resPhases = slices.Clip(c.resPhases)
+	child.reqHooks = slices.Clip(c.reqHooks)
+	child.decodedHooks = slices.Clip(c.decodedHooks)
+	child.errHooks = slices.Clip(c.errHooks)
+	child.panicHooks = slices.Clip(c.panicHooks)
+	child.afterHooks = slices.Clip(c.afterHooks)
+	child.providers = slices.Clip(c.providers)
+	child.decodings = slices.Clip(c.decodings)
+	child.encodings = slices.Clip(c.encodings)
+	child.middleware = slices.Clip(c.middleware)
+	child.callMiddleware = slices.Clip(c.callMiddleware)
+
+	Options(opts...).apply(&child)
+	return &child
+}
+
 // Handle will initiate an http handler that handles request according to
-// the Codec configuration.
-func (c *Codec) Handle(f interface{}) http.Handler {
+// the Codec configuration. The function 'f' can take a context, the
+// *http.Request, a ResponseWriter, provided arguments and inputs that are
+// bound from the request. Only one input is decoded from the body, additional
+// inputs must implement SkipDecode and are bound by request hooks. It panics
+// if 'f' has an unsupported signature, e.g. an argument without a provider.
+//
+// Options can be provided to override the configuration for just this
+// handler, as if the handler was created by a Codec derived using With.
+func (c *Codec) Handle(f interface{}, opts ...Option) http.Handler {
+	if len(opts) > 0 {
+		c = c.With(opts...)
+	}
+
 	switch ft := f.(type) {
 	case func(ResponseWriter, *http.Request):
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			res := NewResponse(w, r, c.reqHooks, c.resHooks, c.errHooks, c.decodings, c.encodings)
+			res := c.newResponse(w, r)
+			defer res.after()
 			defer res.Recover()
-			ft(res, r)
+			res.negotiate(c.decodings, c.encodings)
+			if c.cors != nil && c.cors.handlePreflight(res, res.req) {
+				return
+			}
+
+			c.wrap(res, func(res *response) {
+				outs := c.call(res, nil, func(interface{}) []interface{} {
+					r, span := res.spanRequest("ep.call")
+					defer span.End()
+					defer res.timings.Start("call", "")()
+					ft(res, r)
+					return nil
+				})
+
+				// only render if call middleware provided outputs
+				if len(outs) > 0 {
+					res.Render(outs...)
+				}
+			})
 		})
 	default:
-		clb, err := newCallable(f)
+		clb, err := newCallable(f, c.providers...)
 		if err != nil {
-			panic("ep: failed to turn argument into a handler: " + err.Error())
+			panic("ep: failed to turn " + describeFunc(f) + " into a handler: " + err.Error())
+		}
+
+		for _, ut := range clb.unrenderable {
+			c.logger().Warn("handler returns a type that cannot be rendered",
+				"handler", describeFunc(f), "type", ut.String())
 		}
 
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			res := NewResponse(w, r, c.reqHooks, c.resHooks, c.errHooks, c.decodings, c.encodings)
+			res := c.newResponse(w, r)
+			defer res.after()
 			defer res.Recover()
-
-			ok := true
-			inv := clb.Input()
-			if (inv != reflect.Value{}) {
-				ok = res.Bind(inv.Interface())
+			res.negotiate(c.decodings, c.encodings)
+			if c.cors != nil && c.cors.handlePreflight(res, res.req) {
+				return
 			}
 
-			if ok {
-				res.Render(clb.Call(clb.Args(r, inv))...)
-			}
+			c.wrap(res, func(res *response) {
+				ins := clb.Inputs()
+				if !res.bindInputs(ins) {
+					return
+				}
+
+				outs := c.call(res, callInput(ins), func(in interface{}) []interface{} {
+					return res.call(clb, inputValues(in, len(ins)))
+				})
+
+				// handlers that take a ResponseWriter might have rendered themselves
+				if len(outs) > 0 || !res.wroteHeader {
+					res.Render(outs...)
+				}
+			})
 		})
 	}
 }
+
+// newResponse initializes a response according to the Codec configuration. The
+// request might be replaced so handlers should use the response's request.
+// Negotiation is left to the caller such that any panic it causes can be
+// recovered.
+func (c *Codec) newResponse(w http.ResponseWriter, r *http.Request) *response {
+	if c.requestID != nil {
+		r = c.requestID.withID(r)
+	}
+
+	var di *DebugInfo
+	if c.debug {
+		r, di = withDebugInfo(r)
+	}
+
+	var st *ServerTimings
+	if c.serverTiming {
+		r, st = withServerTimings(r)
+	}
+
+	var root Span
+	if c.tracer != nil {
+		r, root = withRootSpan(r, c.tracer)
+	}
+
+	var logs *slog.Logger
+	if c.logs != nil {
+		r, logs = withLogger(r, c.logs)
+	}
+
+	return &response{
+		ResponseWriter: w,
+		req:            r,
+
+		reqHooks:     c.reqHooks,
+		decodedHooks: c.decodedHooks,
+		resHooks:     c.resHooks,
+		errHooks:     c.errHooks,
+		panicHooks:   c.panicHooks,
+		afterHooks:   c.afterHooks,
+
+		bufferOutputs: c.bufferOutputs,
+		hookConflicts: c.hookConflicts,
+		debug:         di,
+		logs:          logs,
+		tracer:        c.tracer,
+		rootSpan:      root,
+		timings:       st,
+		started:       time.Now(),
+	}
+}
+
+// call the callable with the input values 'ins'
+func (res *response) call(clb *callable, ins []reflect.Value) []interface{} {
+	r, span := res.spanRequest("ep.call")
+	defer span.End()
+	defer res.timings.Start("call", "")()
+
+	args, cleanups, err := clb.Args(res, r, ins)
+	res.cleanups = append(res.cleanups, cleanups...)
+	if err != nil {
+		return []interface{}{err}
+	}
+
+	return clb.Call(args)
+}
+
+// callInput turns the bound inputs into the input that call middleware
+// receives: nil, the single input or a slice with all inputs.
+func callInput(ins []reflect.Value) interface{} {
+	switch len(ins) {
+	case 0:
+		return nil
+	case 1:
+		return ins[0].Interface()
+	}
+
+	all := make([]interface{}, len(ins))
+	for i, in := range ins {
+		all[i] = in.Interface()
+	}
+
+	return all
+}
+
+// inputValues turns the input that call middleware passed on back into the
+// 'n' input values.
+func inputValues(in interface{}, n int) []reflect.Value {
+	switch n {
+	case 0:
+		return nil
+	case 1:
+		return []reflect.Value{reflect.ValueOf(in)}
+	}
+
+	all := in.([]interface{})
+	ins := make([]reflect.Value, n)
+	for i := range ins {
+		ins[i] = reflect.ValueOf(all[i])
+	}
+
+	return ins
+}
+
+// logger returns the Codec's logger or the default logger
+func (c *Codec) logger() *slog.Logger {
+	if c.logs != nil {
+		return c.logs
+	}
+
+	return slog.Default()
+}
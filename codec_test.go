@@ -1,8 +1,11 @@
 package ep
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
@@ -232,3 +235,288 @@ func TestCodecHandleWithoutReflection(t *testing.T) {
 		})
 	}
 }
+
+func TestCodecRequestErrorHooks(t *testing.T) {
+	var calls []string
+	hook1 := func(err error) (out interface{}) {
+		calls = append(calls, "hook1")
+		return nil
+	}
+
+	hook2 := func(r *http.Request, ct string, err error) (out interface{}) {
+		calls = append(calls, "hook2")
+		return struct {
+			Message string `json:"message"`
+		}{r.Method + " " + ct + " " + err.Error()}
+	}
+
+	r := httptest.NewRequest("DELETE", "/", nil)
+	w := httptest.NewRecorder()
+	New(
+		ResponseEncoding(epcoding.JSON{}),
+		ErrorHook(hook1),
+		RequestErrorHook(hook2),
+		ErrorHook(hook1), // should not be called
+	).Handle(func() error { return errors.New("foo") }).ServeHTTP(w, r)
+
+	if w.Body.String() != `{"message":"DELETE application/json foo"}`+"\n" {
+		t.Fatalf("unexpected, got: %v", w.Body.String())
+	}
+
+	if strings.Join(calls, ",") != "hook1,hook2" {
+		t.Fatalf("unexpected hook calls, got: %v", calls)
+	}
+}
+
+type panicDecoding struct{}
+
+func (panicDecoding) Accepts() string                          { return "application/json" }
+func (panicDecoding) Decoder(r *http.Request) epcoding.Decoder { panic("decoding") }
+
+func TestCodecRecoverNegotiationPanic(t *testing.T) {
+	var panics []interface{}
+	r := httptest.NewRequest("POST", "/", strings.NewReader(`{}`))
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	New(
+		RequestDecoding(panicDecoding{}),
+		ResponseEncoding(epcoding.JSON{}),
+		ResponseHook(func(w http.ResponseWriter, r *http.Request, out interface{}) {
+			if _, ok := out.(error); ok {
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		}),
+		ErrorHook(func(err error) interface{} { return err }),
+		PanicHook(func(r *http.Request, v interface{}, stack []byte) { panics = append(panics, v) }),
+	).Handle(func() {}).ServeHTTP(w, r)
+
+	if len(panics) != 1 || panics[0] != "decoding" {
+		t.Fatalf("unexpected panics, got: %v", panics)
+	}
+
+	if w.Code != http.StatusInternalServerError || w.Body.String() != "{}\n" {
+		t.Fatalf("unexpected response, got: %v %v", w.Code, w.Body.String())
+	}
+}
+
+func TestCodecAfterHook(t *testing.T) {
+	for i, c := range []struct {
+		handle    interface{}
+		expStatus int
+		expBytes  int64
+		expOutput interface{}
+		expErr    string
+	}{
+		{handle: func() {}, expStatus: 200},
+		{handle: func() struct{ Foo string } { return struct{ Foo string }{"bar"} },
+			expStatus: 200, expBytes: 14, expOutput: struct{ Foo string }{"bar"}},
+		{handle: func() error { return errors.New("foo") },
+			expStatus: 500, expBytes: 18, expOutput: struct{ Message string }{"foo"}, expErr: "foo"},
+		{handle: func() interface{} { return make(chan struct{}) },
+			expStatus: 500, expBytes: 18, expErr: "response.render: response body encoder failed: json: unsupported type: chan struct {}"},
+		{handle: func(w ResponseWriter, r *http.Request) { w.Write([]byte("foo")) },
+			expStatus: 200, expBytes: 3},
+		{handle: func(w ResponseWriter, r *http.Request) { panic("foo") },
+			expStatus: 500, expBytes: 18, expErr: "foo"},
+	} {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			var outcomes []Outcome
+			r := httptest.NewRequest("GET", "/", nil)
+			w := httptest.NewRecorder()
+			New(
+				ResponseEncoding(epcoding.JSON{}),
+				ResponseHook(func(w http.ResponseWriter, r *http.Request, out interface{}) {
+					if _, ok := out.(struct{ Message string }); ok {
+						w.WriteHeader(http.StatusInternalServerError)
+					}
+				}),
+				ErrorHook(func(err error) interface{} {
+					msg := err.Error()
+					if len(msg) > 3 {
+						msg = msg[len(msg)-3:] // keep bytes predictable
+					}
+
+					return struct{ Message string }{msg}
+				}),
+				AfterHook(func(r *http.Request, o Outcome) { outcomes = append(outcomes, o) }),
+			).Handle(c.handle).ServeHTTP(w, r)
+
+			if len(outcomes) != 1 {
+				t.Fatalf("after hook should be called once, got: %v", len(outcomes))
+			}
+
+			o := outcomes[0]
+			if o.Status != c.expStatus || o.Status != w.Code {
+				t.Fatalf("unexpected status, got: %v (response: %v)", o.Status, w.Code)
+			}
+
+			if o.Written != c.expBytes || o.Written != int64(w.Body.Len()) {
+				t.Fatalf("unexpected bytes written, got: %v (response: %v)", o.Written, w.Body.Len())
+			}
+
+			if c.expOutput != nil && o.Output != c.expOutput {
+				t.Fatalf("unexpected output, got: %#v", o.Output)
+			}
+
+			if (o.Err == nil && c.expErr != "") || (o.Err != nil && !strings.HasSuffix(o.Err.Error(), c.expErr)) {
+				t.Fatalf("unexpected error, got: %v", o.Err)
+			}
+
+			if o.Duration <= 0 {
+				t.Fatalf("should have measured duration, got: %v", o.Duration)
+			}
+		})
+	}
+}
+
+func TestCodecWith(t *testing.T) {
+	type output struct{ Foo string }
+	handle := func() interface{} { return output{"bar"} }
+
+	var calls []string
+	hook := func(name string) ResponseHook {
+		return func(w http.ResponseWriter, r *http.Request, out interface{}) { calls = append(calls, name) }
+	}
+
+	// give the parent's slices spare capacity to detect aliasing
+	parent := New(ResponseEncoding(epcoding.JSON{}), ResponseHook(hook("p1")), ResponseHook(hook("p2")), ResponseHook(hook("p3")))
+	child1 := parent.With(ResponseHook(hook("c1")))
+	child2 := parent.With(ResponseHook(hook("c2")), ClearResponseEncodings(), ResponseEncoding(epcoding.XML{}))
+	child3 := child1.With(ClearResponseHooks(), ResponseHook(hook("c3")))
+
+	for i, c := range []struct {
+		h        http.Handler
+		expCalls string
+		expBody  string
+	}{
+		{parent.Handle(handle), "p1,p2,p3", `{"Foo":"bar"}` + "\n"},
+		{child1.Handle(handle), "p1,p2,p3,c1", `{"Foo":"bar"}` + "\n"},
+		{child2.Handle(handle), "p1,p2,p3,c2", `<output><Foo>bar</Foo></output>`},
+		{child3.Handle(handle), "c3", `{"Foo":"bar"}` + "\n"},
+		{parent.Handle(handle, ResponseHook(hook("h1"))), "p1,p2,p3,h1", `{"Foo":"bar"}` + "\n"},
+		{child1.Handle(handle, ClearResponseHooks()), "", `{"Foo":"bar"}` + "\n"},
+	} {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			calls = nil
+			w := httptest.NewRecorder()
+			c.h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+			if strings.Join(calls, ",") != c.expCalls {
+				t.Fatalf("expected calls %v, got: %v", c.expCalls, calls)
+			}
+
+			if w.Body.String() != c.expBody {
+				t.Fatalf("expected body %v, got: %v", c.expBody, w.Body.String())
+			}
+		})
+	}
+}
+
+type headerInput struct{ Agent string }
+
+func (headerInput) SkipDecode() bool { return true }
+
+func TestCodecHandleSignatures(t *testing.T) {
+	type input struct{ Foo string }
+
+	var mwIn interface{}
+	hooks := Options(
+		RequestHook(func(r *http.Request, in interface{}) error {
+			if hi, ok := in.(*headerInput); ok {
+				hi.Agent = r.Header.Get("User-Agent")
+			}
+
+			return nil
+		}),
+		CallMiddleware(func(next Call) Call {
+			return func(w ResponseWriter, r *http.Request, in interface{}) []interface{} {
+				mwIn = in
+				return next(w, r, in)
+			}
+		}),
+	)
+
+	for i, c := range []struct {
+		fn      interface{}
+		expBody string
+		expIns  int
+	}{
+		{func(r *http.Request, in input) string { return r.Method + " " + in.Foo }, `"POST bar"` + "\n", 1},
+		{func(in *input, w ResponseWriter) { w.Render("rendered " + in.Foo) }, `"rendered bar"` + "\n", 1},
+		{func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("written")) }, `written`, 0},
+		{func(w ResponseWriter, in *input) {}, ``, 1},
+		{func(ctx context.Context, hi headerInput, in *input, r *http.Request) (string, error) {
+			return hi.Agent + " " + in.Foo, nil
+		}, `"ep-test bar"` + "\n", 2},
+	} {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			mwIn = nil
+			r := httptest.NewRequest("POST", "/", strings.NewReader(`{"Foo":"bar"}`))
+			r.Header.Set("Content-Type", "application/json")
+			r.Header.Set("User-Agent", "ep-test")
+			w := httptest.NewRecorder()
+			New(
+				RequestDecoding(epcoding.JSON{}),
+				ResponseEncoding(epcoding.JSON{}),
+				RequestID(RequestIDConfig{}),
+				hooks,
+			).Handle(c.fn).ServeHTTP(w, r)
+
+			if w.Body.String() != c.expBody {
+				t.Fatalf("expected body %v, got: %v", c.expBody, w.Body.String())
+			}
+
+			// response hooks should run, even if the handler wrote nothing
+			if w.Header().Get("X-Request-ID") == "" {
+				t.Fatalf("expected request id header, got: %v", w.Header())
+			}
+
+			switch c.expIns {
+			case 0:
+				if mwIn != nil {
+					t.Fatalf("expected no input, got: %v", mwIn)
+				}
+			case 1:
+				if _, ok := mwIn.(*input); !ok {
+					t.Fatalf("expected single input, got: %v", mwIn)
+				}
+			default:
+				if ins, ok := mwIn.([]interface{}); !ok || len(ins) != c.expIns {
+					t.Fatalf("expected %d inputs, got: %v", c.expIns, mwIn)
+				}
+			}
+		})
+	}
+}
+
+func TestCodecHandleInvalidSignature(t *testing.T) {
+	for i, c := range []struct {
+		fn       interface{}
+		expPanic string
+	}{
+		{"foo", "ep: failed to turn string into a handler: argument is not a function"},
+		{func() (error, error) { return nil, nil }, "(func() (error, error)) into a handler: function can return at most one error"},
+		{func(a, b string) {}, "(func(string, string)) into a handler: function can only have one input argument, 'string' is not provided"},
+	} {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			defer func() {
+				v, _ := recover().(string)
+				if !strings.Contains(v, c.expPanic) {
+					t.Fatalf("expected panic to contain %q, got: %q", c.expPanic, v)
+				}
+			}()
+
+			New().Handle(c.fn)
+		})
+	}
+}
+
+func TestCodecHandleUnrenderableWarning(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	New(Logger(slog.New(slog.NewTextHandler(buf, nil)))).Handle(func() (chan int, error) { return nil, nil })
+
+	if !strings.Contains(buf.String(), `msg="handler returns a type that cannot be rendered"`) ||
+		!strings.Contains(buf.String(), `type="chan int"`) {
+		t.Fatalf("expected warning, got: %v", buf.String())
+	}
+}
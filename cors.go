@@ -0,0 +1,221 @@
+package ep
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSConfig configures how the Codec handles Cross-Origin Resource Sharing
+type CORSConfig struct {
+
+	// AllowOrigins lists the origins that may access the resources. An origin
+	// can be matched exactly, a single "*" allows any origin and a "*" inside
+	// the origin matches any part, e.g: "https://*.example.com"
+	AllowOrigins []string
+
+	// AllowOriginFunc can be provided to decide on the origin with custom
+	// logic. It is consulted when none of the AllowOrigins matched.
+	AllowOriginFunc func(origin string) bool
+
+	// AllowMethods lists the methods the client may use for the actual
+	// request. If empty it defaults to the simple methods: GET, HEAD and POST
+	AllowMethods []string
+
+	// AllowHeaders lists the non-simple headers the client may send with the
+	// actual request. A single "*" allows any header.
+	AllowHeaders []string
+
+	// ExposeHeaders lists the response headers that the client may read
+	ExposeHeaders []string
+
+	// AllowCredentials indicates whether the request may include cookies and
+	// other user credentials. The allowed origin is always echoed instead of
+	// a "*" when enabled.
+	AllowCredentials bool
+
+	// MaxAge determines how long the result of a preflight request may be
+	// cached by the client. Zero means the header is not sent.
+	MaxAge time.Duration
+}
+
+// CORS option enables Cross-Origin Resource Sharing for every handler created
+// by the Codec. Preflight requests are answered directly without invoking the
+// handler, while other responses (including rendered errors) receive the
+// CORS headers through a response hook.
+func CORS(cfg CORSConfig) Option {
+	if len(cfg.AllowMethods) < 1 {
+		cfg.AllowMethods = []string{http.MethodGet, http.MethodHead, http.MethodPost}
+	}
+
+	return &cors{cfg}
+}
+
+type cors struct{ CORSConfig }
+
+func (o *cors) apply(c *Codec) {
+	c.cors = o
+	c.addResponseHook(firstPhase, o.hook)
+}
+
+// hook is the response hook that adds CORS headers to non-preflight responses
+func (o *cors) hook(w http.ResponseWriter, r *http.Request, out interface{}) {
+	if isPreflight(r) {
+		return // handlePreflight already added the headers
+	}
+
+	w.Header().Add("Vary", "Origin")
+
+	origin := r.Header.Get("Origin")
+	if origin == "" || !o.allowOrigin(origin) {
+		return
+	}
+
+	o.setOrigin(w.Header(), origin)
+	if len(o.ExposeHeaders) > 0 {
+		w.Header().Set("Access-Control-Expose-Headers", strings.Join(o.ExposeHeaders, ", "))
+	}
+}
+
+// handlePreflight will respond to the request if it is a CORS preflight
+// request. It returns false if the request should be handled as usual.
+func (o *cors) handlePreflight(w http.ResponseWriter, r *http.Request) bool {
+	if !isPreflight(r) {
+		return false
+	}
+
+	origin := r.Header.Get("Origin")
+	method := r.Header.Get("Access-Control-Request-Method")
+
+	h := w.Header()
+	h.Add("Vary", "Origin")
+	h.Add("Vary", "Access-Control-Request-Method")
+	h.Add("Vary", "Access-Control-Request-Headers")
+
+	// A preflight that is not allowed still gets a response, the client will
+	// refuse the actual request because the headers are missing.
+	o.allowPreflight(h, r, origin, method)
+	w.WriteHeader(http.StatusNoContent)
+	return true
+}
+
+// allowPreflight adds the headers that allow the preflight request, if it is
+func (o *cors) allowPreflight(h http.Header, r *http.Request, origin, method string) {
+	if !o.allowOrigin(origin) || !o.allowMethod(method) {
+		return
+	}
+
+	reqHeaders := splitHeader(r.Header.Get("Access-Control-Request-Headers"))
+	if !o.allowHeaders(reqHeaders) {
+		return
+	}
+
+	o.setOrigin(h, origin)
+	h.Set("Access-Control-Allow-Methods", strings.Join(o.AllowMethods, ", "))
+	if len(reqHeaders) > 0 {
+		h.Set("Access-Control-Allow-Headers", strings.Join(reqHeaders, ", "))
+	}
+
+	if o.MaxAge > 0 {
+		h.Set("Access-Control-Max-Age", strconv.Itoa(int(o.MaxAge/time.Second)))
+	}
+}
+
+// isPreflight returns whether the request is a CORS preflight request
+func isPreflight(r *http.Request) bool {
+	return r.Method == http.MethodOptions && r.Header.Get("Origin") != "" &&
+		r.Header.Get("Access-Control-Request-Method") != ""
+}
+
+func (o *cors) setOrigin(h http.Header, origin string) {
+	if o.AllowCredentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+		h.Set("Access-Control-Allow-Origin", origin)
+		return
+	}
+
+	if o.allowAny() {
+		h.Set("Access-Control-Allow-Origin", "*")
+		return
+	}
+
+	h.Set("Access-Control-Allow-Origin", origin)
+}
+
+func (o *cors) allowAny() bool {
+	for _, ao := range o.AllowOrigins {
+		if ao == "*" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (o *cors) allowOrigin(origin string) bool {
+	for _, ao := range o.AllowOrigins {
+		if matchOrigin(ao, origin) {
+			return true
+		}
+	}
+
+	if o.AllowOriginFunc != nil {
+		return o.AllowOriginFunc(origin)
+	}
+
+	return false
+}
+
+func (o *cors) allowMethod(method string) bool {
+	for _, am := range o.AllowMethods {
+		if strings.EqualFold(am, method) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (o *cors) allowHeaders(headers []string) bool {
+	for _, h := range headers {
+		var ok bool
+		for _, ah := range o.AllowHeaders {
+			if ah == "*" || strings.EqualFold(ah, h) {
+				ok = true
+				break
+			}
+		}
+
+		if !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// matchOrigin returns whether the origin matches the pattern, the pattern may
+// contain at most one "*" wildcard.
+func matchOrigin(pattern, origin string) bool {
+	i := strings.IndexByte(pattern, '*')
+	if i < 0 {
+		return strings.EqualFold(pattern, origin)
+	}
+
+	prefix, suffix := pattern[:i], pattern[i+1:]
+	return len(origin) >= len(prefix)+len(suffix) &&
+		strings.HasPrefix(strings.ToLower(origin), strings.ToLower(prefix)) &&
+		strings.HasSuffix(strings.ToLower(origin), strings.ToLower(suffix))
+}
+
+// splitHeader splits a comma separated header value into its trimmed parts
+func splitHeader(v string) (parts []string) {
+	for _, p := range strings.Split(v, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			parts = append(parts, p)
+		}
+	}
+
+	return
+}
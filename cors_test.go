@@ -0,0 +1,267 @@
+package ep
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/advanderveer/ep/epcoding"
+)
+
+func TestMatchOrigin(t *testing.T) {
+	for i, c := range []struct {
+		pattern string
+		origin  string
+		exp     bool
+	}{
+		{"https://foo.com", "https://foo.com", true},
+		{"https://foo.com", "https://FOO.com", true},
+		{"https://foo.com", "https://bar.com", false},
+		{"*", "https://bar.com", true},
+		{"https://*.foo.com", "https://a.foo.com", true},
+		{"https://*.foo.com", "https://foo.com", false},
+		{"https://*.foo.com", "http://a.foo.com", false},
+		{"https://*.foo.com", "https://a.bar.com", false},
+	} {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			if act := matchOrigin(c.pattern, c.origin); act != c.exp {
+				t.Fatalf("expected: %v, got: %v", c.exp, act)
+			}
+		})
+	}
+}
+
+func TestCORSPreflight(t *testing.T) {
+	var called bool
+	handle := func() { called = true }
+
+	for i, c := range []struct {
+		cfg       CORSConfig
+		origin    string
+		method    string
+		headers   string
+		expHeader http.Header
+	}{
+		{ // not allowed origin gets no CORS headers
+			cfg:    CORSConfig{AllowOrigins: []string{"https://foo.com"}},
+			origin: "https://bar.com", method: "GET",
+			expHeader: http.Header{
+				"Vary": {"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"},
+			},
+		},
+		{ // not allowed method gets no CORS headers
+			cfg:    CORSConfig{AllowOrigins: []string{"https://foo.com"}},
+			origin: "https://foo.com", method: "DELETE",
+			expHeader: http.Header{
+				"Vary": {"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"},
+			},
+		},
+		{ // not allowed header gets no CORS headers
+			cfg:    CORSConfig{AllowOrigins: []string{"https://foo.com"}},
+			origin: "https://foo.com", method: "GET", headers: "X-Foo",
+			expHeader: http.Header{
+				"Vary": {"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"},
+			},
+		},
+		{
+			cfg: CORSConfig{
+				AllowOrigins: []string{"https://*.foo.com"},
+				AllowMethods: []string{"GET", "DELETE"},
+				AllowHeaders: []string{"x-foo"},
+				MaxAge:       time.Minute,
+			},
+			origin: "https://a.foo.com", method: "DELETE", headers: "X-Foo",
+			expHeader: http.Header{
+				"Vary":                         {"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"},
+				"Access-Control-Allow-Origin":  {"https://a.foo.com"},
+				"Access-Control-Allow-Methods": {"GET, DELETE"},
+				"Access-Control-Allow-Headers": {"X-Foo"},
+				"Access-Control-Max-Age":       {"60"},
+			},
+		},
+		{
+			cfg: CORSConfig{
+				AllowOriginFunc:  func(o string) bool { return o == "https://bar.com" },
+				AllowHeaders:     []string{"*"},
+				AllowCredentials: true,
+			},
+			origin: "https://bar.com", method: "POST", headers: "X-Foo, X-Bar",
+			expHeader: http.Header{
+				"Vary":                             {"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"},
+				"Access-Control-Allow-Origin":      {"https://bar.com"},
+				"Access-Control-Allow-Credentials": {"true"},
+				"Access-Control-Allow-Methods":     {"GET, HEAD, POST"},
+				"Access-Control-Allow-Headers":     {"X-Foo, X-Bar"},
+			},
+		},
+	} {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			called = false
+			r := httptest.NewRequest("OPTIONS", "/", nil)
+			r.Header.Set("Origin", c.origin)
+			r.Header.Set("Access-Control-Request-Method", c.method)
+			if c.headers != "" {
+				r.Header.Set("Access-Control-Request-Headers", c.headers)
+			}
+
+			w := httptest.NewRecorder()
+			New(CORS(c.cfg)).Handle(handle).ServeHTTP(w, r)
+
+			if called {
+				t.Fatalf("handler should not be called for preflight")
+			}
+
+			if w.Code != http.StatusNoContent {
+				t.Fatalf("expected code: %d, got: %d", http.StatusNoContent, w.Code)
+			}
+
+			if !reflect.DeepEqual(c.expHeader, w.Header()) {
+				t.Fatalf("expected headers: %v, got: %v", c.expHeader, w.Header())
+			}
+		})
+	}
+}
+
+func TestCORSResponse(t *testing.T) {
+	errHook := func(err error) (out interface{}) {
+		return struct {
+			Message string `json:"message"`
+		}{err.Error()}
+	}
+
+	for i, c := range []struct {
+		cfg       CORSConfig
+		method    string
+		origin    string
+		handle    interface{}
+		expBody   string
+		expHeader http.Header
+	}{
+		{ // without an origin only the vary header is set
+			cfg:     CORSConfig{AllowOrigins: []string{"*"}},
+			method:  "GET",
+			handle:  func() string { return "foo" },
+			expBody: `"foo"` + "\n",
+			expHeader: http.Header{
+				"Vary":                   {"Origin"},
+				"Content-Type":           {"application/json"},
+				"X-Content-Type-Options": {"nosniff"},
+			},
+		},
+		{
+			cfg:     CORSConfig{AllowOrigins: []string{"*"}, ExposeHeaders: []string{"X-Foo"}},
+			method:  "GET",
+			origin:  "https://foo.com",
+			handle:  func() string { return "foo" },
+			expBody: `"foo"` + "\n",
+			expHeader: http.Header{
+				"Vary":                          {"Origin"},
+				"Access-Control-Allow-Origin":   {"*"},
+				"Access-Control-Expose-Headers": {"X-Foo"},
+				"Content-Type":                  {"application/json"},
+				"X-Content-Type-Options":        {"nosniff"},
+			},
+		},
+		{ // rendered errors also get the headers
+			cfg:     CORSConfig{AllowOrigins: []string{"https://foo.com"}},
+			method:  "GET",
+			origin:  "https://foo.com",
+			handle:  func() error { return errors.New("foo") },
+			expBody: `{"message":"foo"}` + "\n",
+			expHeader: http.Header{
+				"Vary":                        {"Origin"},
+				"Access-Control-Allow-Origin": {"https://foo.com"},
+				"Content-Type":                {"application/json"},
+				"X-Content-Type-Options":      {"nosniff"},
+			},
+		},
+		{ // options without preflight headers is handled as usual
+			cfg:     CORSConfig{AllowOrigins: []string{"https://foo.com"}},
+			method:  "OPTIONS",
+			origin:  "https://bar.com",
+			handle:  func() string { return "foo" },
+			expBody: `"foo"` + "\n",
+			expHeader: http.Header{
+				"Vary":                   {"Origin"},
+				"Content-Type":           {"application/json"},
+				"X-Content-Type-Options": {"nosniff"},
+			},
+		},
+	} {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			r := httptest.NewRequest(c.method, "/", strings.NewReader(""))
+			if c.origin != "" {
+				r.Header.Set("Origin", c.origin)
+			}
+
+			w := httptest.NewRecorder()
+			New(
+				ResponseEncoding(epcoding.JSON{}),
+				ErrorHook(errHook),
+				CORS(c.cfg),
+			).Handle(c.handle).ServeHTTP(w, r)
+
+			if w.Body.String() != c.expBody {
+				t.Fatalf("expected body: '%s', got: '%s'", c.expBody, w.Body.String())
+			}
+
+			if !reflect.DeepEqual(c.expHeader, w.Header()) {
+				t.Fatalf("expected headers: %v, got: %v", c.expHeader, w.Header())
+			}
+		})
+	}
+}
+
+func TestCORSPreflightHandledByResponse(t *testing.T) {
+	var outcomes []Outcome
+	c := New(
+		ResponseEncoding(epcoding.JSON{}),
+		ErrorHook(func(err error) interface{} { return map[string]string{"message": err.Error()} }),
+		RequestID(RequestIDConfig{}),
+		AfterHook(func(r *http.Request, o Outcome) { outcomes = append(outcomes, o) }),
+	)
+
+	preflight := func() *http.Request {
+		r := httptest.NewRequest("OPTIONS", "/", nil)
+		r.Header.Set("Origin", "https://foo.com")
+		r.Header.Set("Access-Control-Request-Method", "GET")
+		return r
+	}
+
+	w := httptest.NewRecorder()
+	c.Handle(func() {}, CORS(CORSConfig{AllowOrigins: []string{"https://foo.com"}})).ServeHTTP(w, preflight())
+	if w.Code != http.StatusNoContent || w.Header().Get("X-Request-ID") == "" {
+		t.Fatalf("expected 204 with request id, got: %v %v", w.Code, w.Header())
+	}
+
+	if len(outcomes) != 1 || outcomes[0].Status != http.StatusNoContent {
+		t.Fatalf("expected after hook with 204, got: %v", outcomes)
+	}
+
+	// a panicking origin func is recovered and rendered
+	w = httptest.NewRecorder()
+	c.Handle(func() {}, CORS(CORSConfig{AllowOriginFunc: func(string) bool { panic("boom") }})).ServeHTTP(w, preflight())
+	if w.Body.String() != `{"message":"boom"}`+"\n" {
+		t.Fatalf("expected recovered panic to be rendered, got: %v %v", w.Code, w.Body.String())
+	}
+}
+
+func TestCORSClearResponseHooks(t *testing.T) {
+	c := New(
+		CORS(CORSConfig{AllowOrigins: []string{"https://foo.com"}}),
+		RequestID(RequestIDConfig{}),
+	).With(ClearResponseHooks())
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Origin", "https://foo.com")
+	w := httptest.NewRecorder()
+	c.Handle(func() {}).ServeHTTP(w, r)
+	if w.Header().Get("Access-Control-Allow-Origin") != "https://foo.com" || w.Header().Get("X-Request-ID") == "" {
+		t.Fatalf("expected cors and request id headers, got: %v", w.Header())
+	}
+}
@@ -0,0 +1,148 @@
+package ep
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// DebugInfo holds diagnostic information about how the Codec handled a request.
+// It is only recorded when the Codec is configured with the Debug option and
+// can be retrieved from the request context with DebugInfoFromContext.
+type DebugInfo struct {
+	Decoder            string   // type of the negotiated decoder, if any
+	Encoder            string   // type of the negotiated encoder, if any
+	EncoderContentType string   // content type the encoder produces
+	Hooks              []string // hooks that were called, in order
+
+	mu   sync.Mutex
+	body bytes.Buffer
+}
+
+// BodyPreview returns the first bytes of the request body that were read by
+// the handler, at most 'DebugBodyPreviewSize' bytes are recorded.
+func (d *DebugInfo) BodyPreview() []byte {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]byte(nil), d.body.Bytes()...)
+}
+
+// DebugBodyPreviewSize determines how many bytes of the request body are kept
+// in the DebugInfo
+const DebugBodyPreviewSize = 1024
+
+// Debug option enables the recording of DebugInfo for every request. This
+// comes with overhead and it will cause (a preview of) the request body to be
+// kept in memory so it should only be enabled during development.
+func Debug() Option {
+	return debugMode{}
+}
+
+type debugMode struct{}
+
+func (o debugMode) apply(c *Codec) {
+	c.debug = true
+}
+
+type debugInfoKey struct{}
+
+// DebugInfoFromContext returns the DebugInfo that was recorded for the request
+// or nil if the Codec was not configured with the Debug option.
+func DebugInfoFromContext(ctx context.Context) *DebugInfo {
+	di, _ := ctx.Value(debugInfoKey{}).(*DebugInfo)
+	return di
+}
+
+// withDebugInfo returns a shallow copy of the request with debug info in the
+// context and a body that records a preview of what is read.
+func withDebugInfo(r *http.Request) (*http.Request, *DebugInfo) {
+	di := &DebugInfo{}
+	r = r.WithContext(context.WithValue(r.Context(), debugInfoKey{}, di))
+	if r.Body != nil {
+		r.Body = &previewReadCloser{r.Body, di}
+	}
+
+	return r, di
+}
+
+// hook records the hook 'h' being called
+func (d *DebugInfo) hook(kind string, h interface{}) {
+	if d == nil {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.Hooks = append(d.Hooks, kind+": "+funcName(h))
+}
+
+// coders records the negotiated encoder and decoder
+func (d *DebugInfo) coders(res *response) {
+	if d == nil {
+		return
+	}
+
+	if res.dec != nil {
+		d.Decoder = fmt.Sprintf("%T", res.dec)
+	}
+
+	if res.enc != nil {
+		d.Encoder = fmt.Sprintf("%T", res.enc)
+		d.EncoderContentType = res.encContentType
+	}
+}
+
+type previewReadCloser struct {
+	io.ReadCloser
+	di *DebugInfo
+}
+
+func (prc *previewReadCloser) Read(p []byte) (n int, err error) {
+	n, err = prc.ReadCloser.Read(p)
+
+	prc.di.mu.Lock()
+	defer prc.di.mu.Unlock()
+	if left := DebugBodyPreviewSize - prc.di.body.Len(); left > 0 {
+		if left > n {
+			left = n
+		}
+
+		prc.di.body.Write(p[:left])
+	}
+
+	return
+}
+
+// funcName returns a readable name of the function 'f'
+func funcName(f interface{}) string {
+	rf := runtime.FuncForPC(reflect.ValueOf(f).Pointer())
+	if rf == nil {
+		return "unknown"
+	}
+
+	name := rf.Name()
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+
+	return name
+}
+
+// describeFunc describes the handler 'f' for messages, with its signature
+func describeFunc(f interface{}) string {
+	ft := reflect.TypeOf(f)
+	switch {
+	case ft == nil:
+		return "<nil>"
+	case ft.Kind() != reflect.Func:
+		return ft.String()
+	}
+
+	return funcName(f) + " (" + ft.String() + ")"
+}
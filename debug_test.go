@@ -0,0 +1,84 @@
+package ep
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/advanderveer/ep/epcoding"
+)
+
+func TestDebugInfo(t *testing.T) {
+	var di *DebugInfo
+	reqh := func(r *http.Request, in interface{}) error { return nil }
+	errh := func(r *http.Request, ct string, err error) interface{} {
+		di = DebugInfoFromContext(r.Context())
+		return struct{ Message string }{err.Error()}
+	}
+
+	type input struct{ Name string }
+	r := httptest.NewRequest("POST", "/", strings.NewReader(`{"Name":"foo"}`))
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	New(
+		Debug(),
+		RequestDecoding(epcoding.JSON{}),
+		ResponseEncoding(epcoding.JSON{}),
+		RequestHook(reqh),
+		RequestErrorHook(errh),
+	).Handle(func(in *input) error { return errors.New("foo") }).ServeHTTP(w, r)
+
+	if di == nil {
+		t.Fatalf("expected debug info in request context")
+	}
+
+	if di.Decoder != "*json.Decoder" || di.Encoder != "*json.Encoder" || di.EncoderContentType != "application/json" {
+		t.Fatalf("unexpected coders, got: %v %v %v", di.Decoder, di.Encoder, di.EncoderContentType)
+	}
+
+	if string(di.BodyPreview()) != `{"Name":"foo"}` {
+		t.Fatalf("unexpected body preview, got: %s", di.BodyPreview())
+	}
+
+	if len(di.Hooks) != 2 || !strings.HasPrefix(di.Hooks[0], "request: ") || !strings.HasPrefix(di.Hooks[1], "error: ") {
+		t.Fatalf("unexpected hooks, got: %v", di.Hooks)
+	}
+}
+
+func TestDebugInfoDisabled(t *testing.T) {
+	var called bool
+	errh := func(r *http.Request, ct string, err error) interface{} {
+		called = true
+		if DebugInfoFromContext(r.Context()) != nil {
+			t.Fatalf("should not have debug info without Debug option")
+		}
+
+		return nil
+	}
+
+	r := httptest.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	New(ResponseEncoding(epcoding.JSON{}), RequestErrorHook(errh)).Handle(func() error { return errors.New("foo") }).ServeHTTP(w, r)
+
+	if !called {
+		t.Fatalf("error hook should have been called")
+	}
+}
+
+func TestDebugBodyPreviewSize(t *testing.T) {
+	r := httptest.NewRequest("POST", "/", strings.NewReader(strings.Repeat("a", DebugBodyPreviewSize+10)))
+	r, di := withDebugInfo(r)
+
+	buf := make([]byte, 100)
+	for {
+		if _, err := r.Body.Read(buf); err != nil {
+			break
+		}
+	}
+
+	if len(di.BodyPreview()) != DebugBodyPreviewSize {
+		t.Fatalf("unexpected preview size, got: %v", len(di.BodyPreview()))
+	}
+}
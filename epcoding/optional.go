@@ -0,0 +1,254 @@
+package epcoding
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// OptionalValue is implemented by every Optional field. It allows tooling such
+// as validators and schema generators to reason about optional fields without
+// knowing their type parameter.
+type OptionalValue interface {
+	IsAbsent() bool
+	IsNull() bool
+	IsSet() bool
+	Interface() interface{}
+	ElemType() reflect.Type
+}
+
+type optionalState uint8
+
+const (
+	optionalAbsent optionalState = iota
+	optionalNull
+	optionalSet
+)
+
+// Optional is a field type for inputs that records whether the field was
+// absent from the request body, explicitly set to null or set to a value. It
+// is understood by the JSON and XML decodings and by any form decoding that
+// supports the encoding.TextUnmarshaler interface. This allows handlers for
+// partial updates to only apply the fields that were supplied.
+//
+// The zero value is absent. With Go 1.24 or later the `json:",omitzero"` tag
+// option will omit absent fields while encoding.
+type Optional[T any] struct {
+	value T
+	state optionalState
+}
+
+// Some returns an Optional that is set to value 'v'
+func Some[T any](v T) Optional[T] {
+	return Optional[T]{value: v, state: optionalSet}
+}
+
+// Null returns an Optional that was explicitly set to null
+func Null[T any]() Optional[T] {
+	return Optional[T]{state: optionalNull}
+}
+
+// IsAbsent returns whether the field was not present at all
+func (o Optional[T]) IsAbsent() bool { return o.state == optionalAbsent }
+
+// IsNull returns whether the field was explicitly set to null
+func (o Optional[T]) IsNull() bool { return o.state == optionalNull }
+
+// IsSet returns whether the field was set to a (non-null) value
+func (o Optional[T]) IsSet() bool { return o.state == optionalSet }
+
+// IsZero reports whether the field was absent
+func (o Optional[T]) IsZero() bool { return o.IsAbsent() }
+
+// Get returns the value and whether it was set
+func (o Optional[T]) Get() (T, bool) { return o.value, o.IsSet() }
+
+// Or returns the value if it was set or else the provided default
+func (o Optional[T]) Or(def T) T {
+	if o.IsSet() {
+		return o.value
+	}
+
+	return def
+}
+
+// Interface returns the value if it is set or nil otherwise
+func (o Optional[T]) Interface() interface{} {
+	if !o.IsSet() {
+		return nil
+	}
+
+	return o.value
+}
+
+// ElemType returns the type of the value the field can hold
+func (o Optional[T]) ElemType() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+// MarshalJSON encodes the value, both absent and null fields encode as null
+func (o Optional[T]) MarshalJSON() ([]byte, error) {
+	if !o.IsSet() {
+		return []byte("null"), nil
+	}
+
+	return json.Marshal(o.value)
+}
+
+// UnmarshalJSON is only called when the field is present in the document
+func (o *Optional[T]) UnmarshalJSON(b []byte) error {
+	if bytes.Equal(bytes.TrimSpace(b), []byte("null")) {
+		*o = Null[T]()
+		return nil
+	}
+
+	var v T
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+
+	*o = Some(v)
+	return nil
+}
+
+// MarshalXML encodes the value, absent fields are omitted and null fields are
+// encoded as an empty element with the xsi:nil attribute.
+func (o Optional[T]) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	switch o.state {
+	case optionalAbsent:
+		return nil
+	case optionalNull:
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "xsi:nil"}, Value: "true"})
+		if err := e.EncodeToken(start); err != nil {
+			return err
+		}
+
+		return e.EncodeToken(start.End())
+	default:
+		return e.EncodeElement(o.value, start)
+	}
+}
+
+// UnmarshalXML is only called when the element is present in the document. An
+// element with the xsi:nil attribute set to true is considered null.
+func (o *Optional[T]) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	for _, attr := range start.Attr {
+		if attr.Name.Local == "nil" && attr.Value == "true" &&
+			(attr.Name.Space == "xsi" || attr.Name.Space == "http://www.w3.org/2001/XMLSchema-instance") {
+			*o = Null[T]()
+			return d.Skip()
+		}
+	}
+
+	var v T
+	if err := d.DecodeElement(&v, &start); err != nil {
+		return err
+	}
+
+	*o = Some(v)
+	return nil
+}
+
+// MarshalXMLAttr encodes the value as an attribute, which is omitted when the
+// field is absent or null.
+func (o Optional[T]) MarshalXMLAttr(name xml.Name) (xml.Attr, error) {
+	if !o.IsSet() {
+		return xml.Attr{}, nil
+	}
+
+	b, err := o.MarshalText()
+	if err != nil {
+		return xml.Attr{}, err
+	}
+
+	return xml.Attr{Name: name, Value: string(b)}, nil
+}
+
+// UnmarshalXMLAttr is only called when the attribute is present
+func (o *Optional[T]) UnmarshalXMLAttr(attr xml.Attr) error {
+	return o.UnmarshalText([]byte(attr.Value))
+}
+
+// MarshalText encodes the value as text, absent and null fields are empty
+func (o Optional[T]) MarshalText() ([]byte, error) {
+	if !o.IsSet() {
+		return []byte{}, nil
+	}
+
+	if tm, ok := interface{}(o.value).(encoding.TextMarshaler); ok {
+		return tm.MarshalText()
+	}
+
+	return []byte(fmt.Sprint(o.value)), nil
+}
+
+// UnmarshalText is called by form decoders when the field is present. An empty
+// text is considered null unless the value is a string.
+func (o *Optional[T]) UnmarshalText(b []byte) error {
+	var v T
+	if tu, ok := interface{}(&v).(encoding.TextUnmarshaler); ok {
+		if err := tu.UnmarshalText(b); err != nil {
+			return err
+		}
+
+		*o = Some(v)
+		return nil
+	}
+
+	rv := reflect.ValueOf(&v).Elem()
+	if len(b) < 1 && rv.Kind() != reflect.String {
+		*o = Null[T]()
+		return nil
+	}
+
+	if err := setText(rv, string(b)); err != nil {
+		return err
+	}
+
+	*o = Some(v)
+	return nil
+}
+
+// setText parses the text 's' into the basic value 'rv'
+func setText(rv reflect.Value, s string) error {
+	switch rv.Kind() {
+	case reflect.String:
+		rv.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+
+		rv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, rv.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		rv.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(s, 10, rv.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		rv.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, rv.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		rv.SetFloat(f)
+	default:
+		return fmt.Errorf("cannot decode text into %s", rv.Type())
+	}
+
+	return nil
+}
@@ -0,0 +1,145 @@
+package epcoding
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+type optionalInput struct {
+	XMLName xml.Name           `json:"-" xml:"Input"`
+	Name    Optional[string]   `json:"name"`
+	Age     Optional[int]      `json:"age" xml:"age,attr"`
+	IP      Optional[net.IP]   `json:"ip"`
+	Tags    Optional[[]string] `json:"tags"`
+}
+
+func TestOptionalJSON(t *testing.T) {
+	for i, c := range []struct {
+		body   string
+		expIn  optionalInput
+		expOut string
+	}{
+		{`{}`, optionalInput{}, `{"name":null,"age":null,"ip":null,"tags":null}`},
+		{
+			`{"name":null,"age":5}`,
+			optionalInput{Name: Null[string](), Age: Some(5)},
+			`{"name":null,"age":5,"ip":null,"tags":null}`,
+		},
+		{
+			`{"name":"foo","ip":"127.0.0.1","tags":[]}`,
+			optionalInput{Name: Some("foo"), IP: Some(net.ParseIP("127.0.0.1")), Tags: Some([]string{})},
+			`{"name":"foo","age":null,"ip":"127.0.0.1","tags":[]}`,
+		},
+	} {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			var in optionalInput
+			if err := json.Unmarshal([]byte(c.body), &in); err != nil {
+				t.Fatalf("unexpected, got: %v", err)
+			}
+
+			if !reflect.DeepEqual(in, c.expIn) {
+				t.Fatalf("expected: %#v, got: %#v", c.expIn, in)
+			}
+
+			b, err := json.Marshal(in)
+			if err != nil {
+				t.Fatalf("unexpected, got: %v", err)
+			}
+
+			if string(b) != c.expOut {
+				t.Fatalf("expected: %s, got: %s", c.expOut, b)
+			}
+		})
+	}
+}
+
+func TestOptionalXML(t *testing.T) {
+	for i, c := range []struct {
+		body   string
+		expIn  optionalInput
+		expOut string
+	}{
+		{`<Input></Input>`, optionalInput{}, `<Input></Input>`},
+		{
+			`<Input xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" age="5"><Name xsi:nil="true"/></Input>`,
+			optionalInput{Name: Null[string](), Age: Some(5)},
+			`<Input age="5"><Name xsi:nil="true"></Name></Input>`,
+		},
+		{
+			`<Input><Name>foo</Name><Tags>a</Tags></Input>`,
+			optionalInput{Name: Some("foo"), Tags: Some([]string{"a"})},
+			`<Input><Name>foo</Name><Tags>a</Tags></Input>`,
+		},
+	} {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			var in optionalInput
+			if err := xml.Unmarshal([]byte(c.body), &in); err != nil {
+				t.Fatalf("unexpected, got: %v", err)
+			}
+
+			in.XMLName = xml.Name{}
+			if !reflect.DeepEqual(in, c.expIn) {
+				t.Fatalf("expected: %#v, got: %#v", c.expIn, in)
+			}
+
+			b, err := xml.Marshal(in)
+			if err != nil {
+				t.Fatalf("unexpected, got: %v", err)
+			}
+
+			if string(b) != c.expOut {
+				t.Fatalf("expected: %s, got: %s", c.expOut, b)
+			}
+		})
+	}
+}
+
+func TestOptionalText(t *testing.T) {
+	var s Optional[string]
+	if err := s.UnmarshalText([]byte("")); err != nil || !s.IsSet() || s.Or("x") != "" {
+		t.Fatalf("empty text should set a string, got: %#v, %v", s, err)
+	}
+
+	var i Optional[int]
+	if err := i.UnmarshalText([]byte("")); err != nil || !i.IsNull() {
+		t.Fatalf("empty text should be null for non-strings, got: %#v, %v", i, err)
+	}
+
+	if err := i.UnmarshalText([]byte("12")); err != nil || i.Or(0) != 12 {
+		t.Fatalf("unexpected, got: %#v, %v", i, err)
+	}
+
+	if err := i.UnmarshalText([]byte("foo")); err == nil {
+		t.Fatalf("expected error for invalid int")
+	}
+
+	var ip Optional[net.IP]
+	if err := ip.UnmarshalText([]byte("127.0.0.1")); err != nil || !ip.IsSet() {
+		t.Fatalf("unexpected, got: %#v, %v", ip, err)
+	}
+
+	b, err := ip.MarshalText()
+	if err != nil || string(b) != "127.0.0.1" {
+		t.Fatalf("unexpected, got: %s, %v", b, err)
+	}
+}
+
+func TestOptionalValue(t *testing.T) {
+	var ov OptionalValue = Some(5)
+	if ov.Interface() != 5 || ov.ElemType() != reflect.TypeOf(0) {
+		t.Fatalf("unexpected, got: %v %v", ov.Interface(), ov.ElemType())
+	}
+
+	ov = Optional[string]{}
+	if !ov.IsAbsent() || ov.Interface() != nil {
+		t.Fatalf("unexpected, got: %#v", ov)
+	}
+
+	if v, ok := Null[int]().Get(); ok || v != 0 {
+		t.Fatalf("unexpected, got: %v %v", v, ok)
+	}
+}
@@ -0,0 +1,638 @@
+package epcoding
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	// InvalidPatch is returned when a patch document is malformed or one of
+	// its operations cannot be applied
+	InvalidPatch = errors.New("invalid patch")
+)
+
+// JSONMergePatch decodes JSON Merge Patch (RFC 7396) request bodies. If the
+// input has a SetMergePatch method (e.g. by embedding the MergePatch type) the
+// patch is handed to the input such that it can be applied later onto a value
+// provided by the handler, using ep.ApplyPatch. Otherwise the patch is applied
+// onto the input directly.
+type JSONMergePatch struct{}
+
+func (_ JSONMergePatch) Accepts() string {
+	return "application/merge-patch+json"
+}
+
+func (_ JSONMergePatch) Decoder(r *http.Request) Decoder {
+	return &mergePatchDecoder{r}
+}
+
+type mergePatchDecoder struct{ r *http.Request }
+
+func (d *mergePatchDecoder) Decode(v interface{}) (err error) {
+	if d.r == nil {
+		return io.EOF
+	}
+
+	defer func() { d.r = nil }() // flag as done
+
+	b, err := ioutil.ReadAll(d.r.Body)
+	if err != nil {
+		return err
+	}
+
+	if !json.Valid(b) {
+		return fmt.Errorf("%w: merge patch is not valid JSON", InvalidPatch)
+	}
+
+	if vt, ok := v.(interface{ SetMergePatch(MergePatch) }); ok {
+		vt.SetMergePatch(MergePatch(b))
+		return nil
+	}
+
+	return MergePatch(b).Apply(v)
+}
+
+// MergePatch holds a JSON Merge Patch document as described in RFC 7396
+type MergePatch json.RawMessage
+
+// SetMergePatch is called by the JSONMergePatch decoder
+func (p *MergePatch) SetMergePatch(mp MergePatch) { *p = mp }
+
+// Apply the merge patch onto the value 'v' which must be a pointer. Handlers
+// should use ep.ApplyPatch such that failures are rendered as decoder errors.
+func (p MergePatch) Apply(v interface{}) error {
+	if len(p) < 1 {
+		return nil
+	}
+
+	var patch interface{}
+	if err := unmarshalNumber(p, &patch); err != nil {
+		return fmt.Errorf("%w: %v", InvalidPatch, err)
+	}
+
+	return patchValue(v, func(doc interface{}) (interface{}, error) {
+		return mergePatch(doc, patch), nil
+	})
+}
+
+// mergePatch implements the MergePatch function as described in RFC 7396
+func mergePatch(target, patch interface{}) interface{} {
+	pobj, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	tobj, ok := target.(map[string]interface{})
+	if !ok {
+		tobj = map[string]interface{}{}
+	}
+
+	for k, pv := range pobj {
+		if pv == nil {
+			delete(tobj, k)
+			continue
+		}
+
+		tobj[k] = mergePatch(tobj[k], pv)
+	}
+
+	return tobj
+}
+
+// JSONPatch decodes JSON Patch (RFC 6902) request bodies. All operations are
+// validated while decoding. If the input has a SetPatch method (e.g. by
+// embedding the Patch type) the patch is handed to the input such that it can
+// be applied later onto a value provided by the handler, using ep.ApplyPatch.
+// Otherwise the patch is applied onto the input directly.
+type JSONPatch struct{}
+
+func (_ JSONPatch) Accepts() string {
+	return "application/json-patch+json"
+}
+
+func (_ JSONPatch) Decoder(r *http.Request) Decoder {
+	return &patchDecoder{r}
+}
+
+type patchDecoder struct{ r *http.Request }
+
+func (d *patchDecoder) Decode(v interface{}) (err error) {
+	if d.r == nil {
+		return io.EOF
+	}
+
+	defer func() { d.r = nil }() // flag as done
+
+	var p Patch
+	if err = json.NewDecoder(d.r.Body).Decode(&p); err != nil {
+		return fmt.Errorf("%w: %v", InvalidPatch, err)
+	}
+
+	if err = p.Validate(); err != nil {
+		return err
+	}
+
+	if vt, ok := v.(interface{ SetPatch(Patch) }); ok {
+		vt.SetPatch(p)
+		return nil
+	}
+
+	return p.Apply(v)
+}
+
+// PatchOperation is a single operation of a JSON Patch document
+type PatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// Patch holds a JSON Patch document as described in RFC 6902
+type Patch []PatchOperation
+
+// SetPatch is called by the JSONPatch decoder
+func (p *Patch) SetPatch(pp Patch) { *p = pp }
+
+// Validate checks if all operations in the patch are well-formed
+func (p Patch) Validate() error {
+	for i, op := range p {
+		if _, err := parsePointer(op.Path); err != nil {
+			return fmt.Errorf("%w: operation %d: %v", InvalidPatch, i, err)
+		}
+
+		switch op.Op {
+		case "add", "replace", "test":
+			if op.Value == nil {
+				return fmt.Errorf("%w: operation %d: '%s' requires a value", InvalidPatch, i, op.Op)
+			}
+		case "move", "copy":
+			from, err := parsePointer(op.From)
+			if err != nil {
+				return fmt.Errorf("%w: operation %d: from: %v", InvalidPatch, i, err)
+			}
+
+			if op.Op == "move" && len(from) > 0 &&
+				strings.HasPrefix(op.Path, op.From+"/") {
+				return fmt.Errorf("%w: operation %d: cannot move into own child", InvalidPatch, i)
+			}
+		case "remove":
+		default:
+			return fmt.Errorf("%w: operation %d: unsupported op '%s'", InvalidPatch, i, op.Op)
+		}
+	}
+
+	return nil
+}
+
+// Apply the patch onto the value 'v' which must be a pointer. Operations are
+// applied in order and if any of them fails the value is left untouched.
+// Handlers should use ep.ApplyPatch such that failures are rendered as decoder
+// errors.
+func (p Patch) Apply(v interface{}) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+
+	return patchValue(v, func(doc interface{}) (_ interface{}, err error) {
+		for i, op := range p {
+			doc, err = op.apply(doc)
+			if err != nil {
+				return nil, fmt.Errorf("%w: operation %d: %v", InvalidPatch, i, err)
+			}
+		}
+
+		return doc, nil
+	})
+}
+
+func (op PatchOperation) apply(doc interface{}) (interface{}, error) {
+	path, _ := parsePointer(op.Path)
+
+	var val interface{}
+	if op.Value != nil {
+		if err := unmarshalNumber(op.Value, &val); err != nil {
+			return nil, err
+		}
+	}
+
+	switch op.Op {
+	case "add":
+		return pointerAdd(doc, path, val)
+	case "remove":
+		return pointerRemove(doc, path)
+	case "replace":
+		if len(path) < 1 {
+			return val, nil
+		}
+
+		doc, err := pointerRemove(doc, path)
+		if err != nil {
+			return nil, err
+		}
+
+		return pointerAdd(doc, path, val)
+	case "move", "copy":
+		from, _ := parsePointer(op.From)
+		fval, err := pointerGet(doc, from)
+		if err != nil {
+			return nil, err
+		}
+
+		if op.Op == "move" {
+			if doc, err = pointerRemove(doc, from); err != nil {
+				return nil, err
+			}
+		} else if fval, err = deepCopy(fval); err != nil {
+			return nil, err
+		}
+
+		return pointerAdd(doc, path, fval)
+	case "test":
+		cur, err := pointerGet(doc, path)
+		if err != nil {
+			return nil, err
+		}
+
+		if !jsonEqual(cur, val) {
+			return nil, fmt.Errorf("test failed for path '%s'", op.Path)
+		}
+
+		return doc, nil
+	}
+
+	return nil, fmt.Errorf("unsupported op '%s'", op.Op)
+}
+
+// patchValue turns 'v' into its generic JSON representation, calls 'f' with it
+// and writes the patched representation back into 'v'.
+func patchValue(v interface{}, f func(doc interface{}) (interface{}, error)) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("%w: patch target must be a non-nil pointer", InvalidPatch)
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	var doc interface{}
+	if err = unmarshalNumber(b, &doc); err != nil {
+		return err
+	}
+
+	// the patch functions modify the document in place
+	orig, err := deepCopy(doc)
+	if err != nil {
+		return err
+	}
+
+	patched, err := f(doc)
+	if err != nil {
+		return err
+	}
+
+	// the patch is applied onto a copy such that the value is left untouched
+	// if it fails, and such that fields JSON cannot see keep their value
+	nv := reflect.New(rv.Elem().Type())
+	nv.Elem().Set(rv.Elem())
+	if err = patchOnto(nv.Elem(), orig, patched); err != nil {
+		return fmt.Errorf("%w: %v", InvalidPatch, err)
+	}
+
+	rv.Elem().Set(nv.Elem())
+	return nil
+}
+
+// patchOnto updates the (addressable) value 'rv' that is represented by the
+// document 'orig' such that it represents the document 'patched'. Only the
+// struct fields of members that changed are decoded, fields of members that
+// were removed are set to their zero value.
+func patchOnto(rv reflect.Value, orig, patched interface{}) error {
+	oobj, ook := orig.(map[string]interface{})
+	pobj, pok := patched.(map[string]interface{})
+	if !ook || !pok || !isPlainStruct(rv) {
+		rv.SetZero()
+		return unmarshalOnto(rv, patched)
+	}
+
+	// don't modify a struct that might be shared with the original value
+	if rv.Kind() == reflect.Ptr {
+		cp := reflect.New(rv.Type().Elem())
+		cp.Elem().Set(rv.Elem())
+		rv.Set(cp)
+		rv = cp.Elem()
+	}
+
+	for name := range oobj {
+		if _, ok := pobj[name]; ok {
+			continue
+		}
+
+		if fv, ok := jsonField(rv, name); ok {
+			fv.SetZero()
+		}
+	}
+
+	for name, pval := range pobj {
+		oval, existed := oobj[name]
+		if existed && jsonEqual(oval, pval) {
+			continue
+		}
+
+		fv, ok := jsonField(rv, name)
+		if !ok {
+			continue // unknown members are ignored, as with json.Unmarshal
+		}
+
+		if !existed {
+			oval = nil
+		}
+
+		if err := patchOnto(fv, oval, pval); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// isPlainStruct reports whether 'rv' is a struct, or a non-nil pointer to one,
+// that doesn't decode itself from JSON.
+func isPlainStruct(rv reflect.Value) bool {
+	if rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+
+	if rv.Kind() != reflect.Struct {
+		return false
+	}
+
+	switch rv.Addr().Interface().(type) {
+	case json.Unmarshaler, encoding.TextUnmarshaler:
+		return false
+	}
+
+	return true
+}
+
+// jsonField returns the field of struct (pointer) 'rv' that encoding/json
+// uses for the member 'name', an exact match is preferred.
+func jsonField(rv reflect.Value, name string) (fv reflect.Value, ok bool) {
+	if rv.Kind() == reflect.Ptr {
+		rv = rv.Elem()
+	}
+
+	var fold []int
+	for _, f := range reflect.VisibleFields(rv.Type()) {
+		tag := f.Tag.Get("json")
+		if !f.IsExported() || tag == "-" {
+			continue
+		}
+
+		fname, _, _ := strings.Cut(tag, ",")
+		if f.Anonymous && fname == "" && indirectType(f.Type).Kind() == reflect.Struct {
+			continue // its fields are promoted
+		}
+
+		if fname == "" {
+			fname = f.Name
+		}
+
+		if fname == name {
+			fold = f.Index
+			break
+		} else if fold == nil && strings.EqualFold(fname, name) {
+			fold = f.Index
+		}
+	}
+
+	if fold == nil {
+		return fv, false
+	}
+
+	fv, err := rv.FieldByIndexErr(fold)
+	return fv, err == nil
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Ptr {
+		return t.Elem()
+	}
+
+	return t
+}
+
+// unmarshalOnto decodes the generic JSON value 'v' into 'rv'
+func unmarshalOnto(rv reflect.Value, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, rv.Addr().Interface())
+}
+
+// parsePointer parses a JSON Pointer (RFC 6901) into its reference tokens
+func parsePointer(p string) ([]string, error) {
+	if p == "" {
+		return nil, nil
+	}
+
+	if p[0] != '/' {
+		return nil, fmt.Errorf("pointer '%s' must start with a slash", p)
+	}
+
+	tokens := strings.Split(p[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(t)
+	}
+
+	return tokens, nil
+}
+
+func pointerGet(node interface{}, tokens []string) (interface{}, error) {
+	for _, t := range tokens {
+		switch nt := node.(type) {
+		case map[string]interface{}:
+			child, ok := nt[t]
+			if !ok {
+				return nil, fmt.Errorf("member '%s' does not exist", t)
+			}
+
+			node = child
+		case []interface{}:
+			i, err := arrayIndex(t, len(nt)-1)
+			if err != nil {
+				return nil, err
+			}
+
+			node = nt[i]
+		default:
+			return nil, fmt.Errorf("cannot reference '%s' in a scalar value", t)
+		}
+	}
+
+	return node, nil
+}
+
+func pointerAdd(node interface{}, tokens []string, val interface{}) (interface{}, error) {
+	if len(tokens) < 1 {
+		return val, nil
+	}
+
+	return pointerUpdate(node, tokens, func(parent interface{}, t string) (interface{}, error) {
+		switch pt := parent.(type) {
+		case map[string]interface{}:
+			pt[t] = val
+			return pt, nil
+		case []interface{}:
+			if t == "-" {
+				return append(pt, val), nil
+			}
+
+			i, err := arrayIndex(t, len(pt))
+			if err != nil {
+				return nil, err
+			}
+
+			pt = append(pt, nil)
+			copy(pt[i+1:], pt[i:])
+			pt[i] = val
+			return pt, nil
+		default:
+			return nil, fmt.Errorf("cannot add '%s' to a scalar value", t)
+		}
+	})
+}
+
+func pointerRemove(node interface{}, tokens []string) (interface{}, error) {
+	if len(tokens) < 1 {
+		return nil, fmt.Errorf("cannot remove the whole document")
+	}
+
+	return pointerUpdate(node, tokens, func(parent interface{}, t string) (interface{}, error) {
+		switch pt := parent.(type) {
+		case map[string]interface{}:
+			if _, ok := pt[t]; !ok {
+				return nil, fmt.Errorf("member '%s' does not exist", t)
+			}
+
+			delete(pt, t)
+			return pt, nil
+		case []interface{}:
+			i, err := arrayIndex(t, len(pt)-1)
+			if err != nil {
+				return nil, err
+			}
+
+			return append(pt[:i], pt[i+1:]...), nil
+		default:
+			return nil, fmt.Errorf("cannot remove '%s' from a scalar value", t)
+		}
+	})
+}
+
+// pointerUpdate walks to the parent of the value referenced by the tokens and
+// calls 'f' to modify it. Updated values are assigned back on the way up since
+// slices might have been re-allocated.
+func pointerUpdate(
+	node interface{},
+	tokens []string,
+	f func(parent interface{}, t string) (interface{}, error),
+) (interface{}, error) {
+	if len(tokens) == 1 {
+		return f(node, tokens[0])
+	}
+
+	switch nt := node.(type) {
+	case map[string]interface{}:
+		child, ok := nt[tokens[0]]
+		if !ok {
+			return nil, fmt.Errorf("member '%s' does not exist", tokens[0])
+		}
+
+		child, err := pointerUpdate(child, tokens[1:], f)
+		if err != nil {
+			return nil, err
+		}
+
+		nt[tokens[0]] = child
+		return nt, nil
+	case []interface{}:
+		i, err := arrayIndex(tokens[0], len(nt)-1)
+		if err != nil {
+			return nil, err
+		}
+
+		child, err := pointerUpdate(nt[i], tokens[1:], f)
+		if err != nil {
+			return nil, err
+		}
+
+		nt[i] = child
+		return nt, nil
+	default:
+		return nil, fmt.Errorf("cannot reference '%s' in a scalar value", tokens[0])
+	}
+}
+
+// arrayIndex parses an array index token that may be at most 'max'
+func arrayIndex(t string, max int) (int, error) {
+	if len(t) > 1 && t[0] == '0' {
+		return 0, fmt.Errorf("array index '%s' has leading zeros", t)
+	}
+
+	i, err := strconv.Atoi(t)
+	if err != nil || i < 0 {
+		return 0, fmt.Errorf("invalid array index '%s'", t)
+	}
+
+	if i > max {
+		return 0, fmt.Errorf("array index '%s' is out of bounds", t)
+	}
+
+	return i, nil
+}
+
+func deepCopy(v interface{}) (c interface{}, err error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	err = unmarshalNumber(b, &c)
+	return
+}
+
+// jsonEqual compares two generic JSON values, numbers are compared by value
+func jsonEqual(a, b interface{}) bool {
+	ab, aerr := json.Marshal(a)
+	bb, berr := json.Marshal(b)
+	if aerr != nil || berr != nil {
+		return false
+	}
+
+	var av, bv interface{}
+	_ = json.Unmarshal(ab, &av)
+	_ = json.Unmarshal(bb, &bv)
+	return reflect.DeepEqual(av, bv)
+}
+
+// unmarshalNumber decodes JSON while keeping numbers as json.Number such that
+// large integers survive the round trip.
+func unmarshalNumber(b []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
@@ -0,0 +1,272 @@
+package epcoding
+
+import (
+	"errors"
+	"io"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type patchTarget struct {
+	Name  string            `json:"name"`
+	Age   int64             `json:"age,omitempty"`
+	Tags  []string          `json:"tags,omitempty"`
+	Attrs map[string]string `json:"attrs,omitempty"`
+}
+
+type patchProfile struct {
+	Bio    string
+	secret string
+}
+
+type patchUser struct {
+	Name     string
+	Password string `json:"-"`
+	Nick     Optional[string]
+	Email    Optional[string]
+	Profile  *patchProfile
+	version  int
+}
+
+func TestMergePatchApply(t *testing.T) {
+	for i, c := range []struct {
+		target interface{}
+		patch  string
+		expErr error
+		exp    interface{}
+	}{
+		{
+			target: &patchTarget{Name: "foo", Age: 9007199254740993},
+			patch:  `{"name":"bar"}`,
+			exp:    &patchTarget{Name: "bar", Age: 9007199254740993},
+		},
+		{
+			target: &patchTarget{Name: "foo", Age: 10, Tags: []string{"a"}},
+			patch:  `{"age":null,"tags":["b","c"]}`,
+			exp:    &patchTarget{Name: "foo", Tags: []string{"b", "c"}},
+		},
+		{
+			target: &patchTarget{Name: "foo", Attrs: map[string]string{"a": "1", "b": "2"}},
+			patch:  `{"attrs":{"a":null,"c":"3"}}`,
+			exp:    &patchTarget{Name: "foo", Attrs: map[string]string{"b": "2", "c": "3"}},
+		},
+		{ // fields that JSON can't see and unchanged optionals are kept
+			target: &patchUser{Name: "a", Password: "hash", Profile: &patchProfile{"x", "s"}, version: 2},
+			patch:  `{"Name":"b","Email":"b@example.com"}`,
+			exp: &patchUser{Name: "b", Password: "hash", Email: Some("b@example.com"),
+				Profile: &patchProfile{"x", "s"}, version: 2},
+		},
+		{
+			target: &patchUser{Name: "a", Nick: Some("n"), Profile: &patchProfile{"x", "s"}},
+			patch:  `{"Nick":null,"Profile":{"Bio":"y"}}`,
+			exp:    &patchUser{Name: "a", Profile: &patchProfile{"y", "s"}},
+		},
+		{
+			target: &patchTarget{Name: "foo"},
+			patch:  `{"name":1}`,
+			expErr: InvalidPatch,
+			exp:    &patchTarget{Name: "foo"},
+		},
+		{
+			target: patchTarget{Name: "foo"},
+			patch:  `{"name":"bar"}`,
+			expErr: InvalidPatch,
+			exp:    patchTarget{Name: "foo"},
+		},
+	} {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			err := MergePatch(c.patch).Apply(c.target)
+			if !errors.Is(err, c.expErr) {
+				t.Fatalf("expected error: %#v, got: %#v", c.expErr, err)
+			}
+
+			if !reflect.DeepEqual(c.target, c.exp) {
+				t.Fatalf("expected: %#v, got: %#v", c.exp, c.target)
+			}
+		})
+	}
+}
+
+func TestPatchApply(t *testing.T) {
+	for i, c := range []struct {
+		target interface{}
+		patch  Patch
+		expErr error
+		exp    interface{}
+	}{
+		{
+			target: &patchTarget{Name: "foo", Tags: []string{"a", "c"}},
+			patch: Patch{
+				{Op: "replace", Path: "/name", Value: []byte(`"bar"`)},
+				{Op: "add", Path: "/tags/1", Value: []byte(`"b"`)},
+				{Op: "add", Path: "/tags/-", Value: []byte(`"d"`)},
+			},
+			exp: &patchTarget{Name: "bar", Tags: []string{"a", "b", "c", "d"}},
+		},
+		{
+			target: &patchTarget{Name: "foo", Age: 10, Tags: []string{"a", "b"}},
+			patch: Patch{
+				{Op: "test", Path: "/age", Value: []byte(`10.0`)},
+				{Op: "remove", Path: "/age"},
+				{Op: "remove", Path: "/tags/0"},
+				{Op: "add", Path: "/attrs", Value: []byte(`{}`)},
+				{Op: "copy", From: "/name", Path: "/attrs/a~1b"},
+				{Op: "move", From: "/tags/0", Path: "/attrs/c"},
+			},
+			exp: &patchTarget{Name: "foo", Tags: []string{}, Attrs: map[string]string{"a/b": "foo", "c": "b"}},
+		},
+		{ // failing test operation should leave the target untouched
+			target: &patchTarget{Name: "foo"},
+			patch: Patch{
+				{Op: "replace", Path: "/name", Value: []byte(`"bar"`)},
+				{Op: "test", Path: "/name", Value: []byte(`"foo"`)},
+			},
+			expErr: InvalidPatch,
+			exp:    &patchTarget{Name: "foo"},
+		},
+		{
+			target: &patchTarget{Name: "foo"},
+			patch:  Patch{{Op: "remove", Path: "/tags/0"}},
+			expErr: InvalidPatch,
+			exp:    &patchTarget{Name: "foo"},
+		},
+		{
+			target: &patchTarget{Name: "foo", Tags: []string{"a"}},
+			patch:  Patch{{Op: "add", Path: "/tags/01", Value: []byte(`"b"`)}},
+			expErr: InvalidPatch,
+			exp:    &patchTarget{Name: "foo", Tags: []string{"a"}},
+		},
+		{
+			target: &patchUser{Name: "a", Password: "hash", Email: Some("a@example.com"), Profile: &patchProfile{"x", "s"}},
+			patch: Patch{
+				{Op: "replace", Path: "/Profile/Bio", Value: []byte(`"y"`)},
+				{Op: "remove", Path: "/Email"},
+			},
+			exp: &patchUser{Name: "a", Password: "hash", Profile: &patchProfile{"y", "s"}},
+		},
+		{
+			target: &patchTarget{Name: "foo"},
+			patch:  Patch{{Op: "replace", Path: "", Value: []byte(`{"name":"bar"}`)}},
+			exp:    &patchTarget{Name: "bar"},
+		},
+	} {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			err := c.patch.Apply(c.target)
+			if !errors.Is(err, c.expErr) {
+				t.Fatalf("expected error: %#v, got: %#v", c.expErr, err)
+			}
+
+			if !reflect.DeepEqual(c.target, c.exp) {
+				t.Fatalf("expected: %#v, got: %#v", c.exp, c.target)
+			}
+		})
+	}
+}
+
+func TestPatchValidate(t *testing.T) {
+	for i, c := range []struct {
+		patch  Patch
+		expErr error
+	}{
+		{Patch{}, nil},
+		{Patch{{Op: "remove", Path: "/a"}}, nil},
+		{Patch{{Op: "foo", Path: "/a"}}, InvalidPatch},
+		{Patch{{Op: "remove", Path: "a"}}, InvalidPatch},
+		{Patch{{Op: "add", Path: "/a"}}, InvalidPatch},
+		{Patch{{Op: "add", Path: "/a", Value: []byte(`null`)}}, nil},
+		{Patch{{Op: "copy", Path: "/a", From: "b"}}, InvalidPatch},
+		{Patch{{Op: "move", Path: "/a/b", From: "/a"}}, InvalidPatch},
+		{Patch{{Op: "move", Path: "/ab", From: "/a"}}, nil},
+	} {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			err := c.patch.Validate()
+			if !errors.Is(err, c.expErr) {
+				t.Fatalf("expected error: %#v, got: %#v", c.expErr, err)
+			}
+		})
+	}
+}
+
+func TestPatchDecodings(t *testing.T) {
+	type Input1 struct {
+		MergePatch
+		ID string
+	}
+
+	type Input2 struct {
+		Patch
+		ID string
+	}
+
+	for i, c := range []struct {
+		dec        Decoding
+		body       string
+		in         interface{}
+		expErr     error
+		expIn      interface{}
+		expAccepts string
+	}{
+		{
+			dec: JSONMergePatch{}, body: `{"name":"bar"}`,
+			in:         &patchTarget{Name: "foo", Age: 1},
+			expIn:      &patchTarget{Name: "bar", Age: 1},
+			expAccepts: "application/merge-patch+json",
+		},
+		{
+			dec: JSONMergePatch{}, body: `{"name":"bar"}`,
+			in:         &Input1{ID: "1"},
+			expIn:      &Input1{MergePatch: MergePatch(`{"name":"bar"}`), ID: "1"},
+			expAccepts: "application/merge-patch+json",
+		},
+		{
+			dec: JSONMergePatch{}, body: `{"name":`,
+			in:         &patchTarget{},
+			expErr:     InvalidPatch,
+			expIn:      &patchTarget{},
+			expAccepts: "application/merge-patch+json",
+		},
+		{
+			dec: JSONPatch{}, body: `[{"op":"replace","path":"/name","value":"bar"}]`,
+			in:         &patchTarget{Name: "foo"},
+			expIn:      &patchTarget{Name: "bar"},
+			expAccepts: "application/json-patch+json",
+		},
+		{
+			dec: JSONPatch{}, body: `[{"op":"replace","path":"/name","value":"bar"}]`,
+			in:         &Input2{ID: "1"},
+			expIn:      &Input2{Patch: Patch{{Op: "replace", Path: "/name", Value: []byte(`"bar"`)}}, ID: "1"},
+			expAccepts: "application/json-patch+json",
+		},
+		{
+			dec: JSONPatch{}, body: `[{"op":"bogus","path":"/name"}]`,
+			in:         &Input2{},
+			expErr:     InvalidPatch,
+			expIn:      &Input2{},
+			expAccepts: "application/json-patch+json",
+		},
+	} {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			if c.dec.Accepts() != c.expAccepts {
+				t.Fatalf("expected decoding to accept '%s', got: '%s'", c.expAccepts, c.dec.Accepts())
+			}
+
+			r := httptest.NewRequest("PATCH", "/", strings.NewReader(c.body))
+			dec := c.dec.Decoder(r)
+			err := dec.Decode(c.in)
+			if !errors.Is(err, c.expErr) {
+				t.Fatalf("expected error: %#v, got: %#v", c.expErr, err)
+			}
+
+			if !reflect.DeepEqual(c.in, c.expIn) {
+				t.Fatalf("expected: %#v, got: %#v", c.expIn, c.in)
+			}
+
+			if err = dec.Decode(c.in); err != io.EOF {
+				t.Fatalf("expected EOF on second decode, got: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package ephook
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/advanderveer/ep"
+)
+
+// Conditional is a response hook that supports conditional GET requests. It
+// asks the output for validators through an ETag() or LastModified() method.
+// If the output has no ETag method but the Codec buffers outputs, a strong
+// ETag is computed from the encoded body and its content type. When the
+// request's If-None-Match or If-Modified-Since header indicates that the
+// client already has the representation a 304 is written and the body is
+// discarded. Outputs can also provide a CacheControl() method to set the
+// Cache-Control header.
+//
+// Unless outputs are buffered the response hooks are called before encoding
+// an output that provides an ETag() or LastModified() method, such that the
+// 304 is written without encoding the output.
+//
+// Since the first hook that writes the header wins, the Status hook should be
+// configured with the StatusHook option such that it is called after this one.
+func Conditional(w http.ResponseWriter, r *http.Request, out interface{}) {
+	if _, ok := out.(error); ok {
+		return
+	}
+
+	if outt, ok := out.(statusOutput); ok && outt.Status() != http.StatusOK {
+		return
+	}
+
+	h := w.Header()
+	if outt, ok := out.(interface{ CacheControl() string }); ok {
+		if cc := outt.CacheControl(); cc != "" {
+			h.Set("Cache-Control", cc)
+		}
+	}
+
+	var etag string
+	if outt, ok := out.(interface{ ETag() string }); ok {
+		etag = quoteETag(outt.ETag())
+	} else if body := ep.EncodedBody(w); body != nil {
+		etag = strongETag(h.Get("Content-Type"), body)
+	}
+
+	if etag != "" {
+		h.Set("ETag", etag)
+	}
+
+	var modified time.Time
+	if outt, ok := out.(interface{ LastModified() time.Time }); ok {
+		if modified = outt.LastModified(); !modified.IsZero() {
+			h.Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+		}
+	}
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return
+	}
+
+	if !notModified(r, etag, modified) {
+		return
+	}
+
+	// same as the std lib, see: net/http/fs.go writeNotModified
+	h.Del("Content-Type")
+	h.Del("Content-Length")
+	h.Del("X-Content-Type-Options")
+	if etag != "" {
+		h.Del("Last-Modified")
+	}
+
+	w.WriteHeader(http.StatusNotModified)
+}
+
+// notModified evaluates the request preconditions, If-None-Match takes
+// precedence over If-Modified-Since as described in RFC 7232, section 6.
+func notModified(r *http.Request, etag string, modified time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		if etag == "" {
+			return false
+		}
+
+		for _, candidate := range strings.Split(inm, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || weakMatch(candidate, etag) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	ims := r.Header.Get("If-Modified-Since")
+	if ims == "" || modified.IsZero() {
+		return false
+	}
+
+	t, err := http.ParseTime(ims)
+	if err != nil {
+		return false
+	}
+
+	return !modified.Truncate(time.Second).After(t)
+}
+
+// weakMatch compares two entity tags using the weak comparison function
+func weakMatch(a, b string) bool {
+	return strings.TrimPrefix(a, "W/") == strings.TrimPrefix(b, "W/")
+}
+
+// quoteETag makes sure the etag is a quoted string as required by the header
+func quoteETag(etag string) string {
+	if etag == "" || strings.HasSuffix(etag, `"`) {
+		return etag
+	}
+
+	return `"` + etag + `"`
+}
+
+// strongETag computes an entity tag for the encoded body of a variant
+func strongETag(ct string, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(ct))
+	hash.Write([]byte{0})
+	hash.Write(body)
+	return `"` + hex.EncodeToString(hash.Sum(nil)[:16]) + `"`
+}
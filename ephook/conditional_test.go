@@ -0,0 +1,165 @@
+package ephook
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/advanderveer/ep"
+	"github.com/advanderveer/ep/epcoding"
+)
+
+var lastModified = time.Date(2020, 5, 1, 10, 0, 0, 0, time.UTC)
+
+type output8 struct{ Foo string }
+
+func (_ output8) ETag() string { return "v1" }
+
+func (_ output8) CacheControl() string { return "max-age=60" }
+
+type output9 struct{ Foo string }
+
+func (_ output9) LastModified() time.Time { return lastModified }
+
+type output10 struct{ encodes *int }
+
+func (_ output10) ETag() string { return "v1" }
+
+func (o output10) MarshalJSON() ([]byte, error) {
+	*o.encodes++
+	return []byte(`{}`), nil
+}
+
+func TestConditionalHook(t *testing.T) {
+	for i, c := range []struct {
+		method   string
+		out      interface{}
+		buffered bool
+		header   http.Header
+		expCode  int
+		expBody  string
+		expETag  string
+		expLM    string
+		expCC    string
+	}{
+		{ // no validators, no conditional response
+			method: "GET", out: struct{ Foo string }{"bar"},
+			header:  http.Header{"If-None-Match": {`"v1"`}},
+			expCode: 200, expBody: `{"Foo":"bar"}` + "\n",
+		},
+		{
+			method: "GET", out: output8{"bar"},
+			expCode: 200, expBody: `{"Foo":"bar"}` + "\n",
+			expETag: `"v1"`, expCC: "max-age=60",
+		},
+		{
+			method: "GET", out: output8{"bar"},
+			header:  http.Header{"If-None-Match": {`"v0", W/"v1"`}},
+			expCode: 304, expETag: `"v1"`, expCC: "max-age=60",
+		},
+		{ // unsafe methods are not conditional
+			method: "POST", out: output8{"bar"},
+			header:  http.Header{"If-None-Match": {`"v1"`}},
+			expCode: 200, expBody: `{"Foo":"bar"}` + "\n",
+			expETag: `"v1"`, expCC: "max-age=60",
+		},
+		{
+			method: "GET", out: output9{"bar"},
+			header:  http.Header{"If-Modified-Since": {lastModified.Format(http.TimeFormat)}},
+			expCode: 304, expLM: lastModified.Format(http.TimeFormat),
+		},
+		{
+			method: "GET", out: output9{"bar"},
+			header:  http.Header{"If-Modified-Since": {lastModified.Add(-time.Hour).Format(http.TimeFormat)}},
+			expCode: 200, expBody: `{"Foo":"bar"}` + "\n",
+			expLM: lastModified.Format(http.TimeFormat),
+		},
+		{ // computed from the buffered body
+			method: "GET", out: struct{ Foo string }{"bar"}, buffered: true,
+			expCode: 200, expBody: `{"Foo":"bar"}` + "\n",
+			expETag: `"1071ab0becac3141d1a41fba4cb4eb3d"`,
+		},
+		{
+			method: "GET", out: struct{ Foo string }{"bar"}, buffered: true,
+			header:  http.Header{"If-None-Match": {`"1071ab0becac3141d1a41fba4cb4eb3d"`}},
+			expCode: 304, expETag: `"1071ab0becac3141d1a41fba4cb4eb3d"`,
+		},
+	} {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			r := httptest.NewRequest(c.method, "/", nil)
+			for k, v := range c.header {
+				r.Header[k] = v
+			}
+
+			var opts []ep.Option
+			if c.buffered {
+				opts = append(opts, ep.BufferOutputs())
+			}
+
+			w := httptest.NewRecorder()
+			ep.New(append(opts,
+				ep.ResponseEncoding(epcoding.JSON{}),
+				ep.ResponseHook(Conditional),
+				StatusHook,
+			)...).Handle(func() interface{} { return c.out }).ServeHTTP(w, r)
+
+			if w.Code != c.expCode {
+				t.Fatalf("expected %d, got: %d", c.expCode, w.Code)
+			}
+
+			if w.Body.String() != c.expBody {
+				t.Fatalf("expected %#v, got: %#v", c.expBody, w.Body.String())
+			}
+
+			if act := w.Header().Get("ETag"); act != c.expETag {
+				t.Fatalf("expected etag %#v, got: %#v", c.expETag, act)
+			}
+
+			if act := w.Header().Get("Last-Modified"); act != c.expLM {
+				t.Fatalf("expected last-modified %#v, got: %#v", c.expLM, act)
+			}
+
+			if act := w.Header().Get("Cache-Control"); act != c.expCC {
+				t.Fatalf("expected cache-control %#v, got: %#v", c.expCC, act)
+			}
+
+			if c.expCode == 304 && w.Header().Get("Content-Type") != "" {
+				t.Fatalf("expected no content-type on 304, got: %v", w.Header())
+			}
+		})
+	}
+}
+
+func TestConditionalWithoutEncoding(t *testing.T) {
+	for i, c := range []struct {
+		inm        string
+		expCode    int
+		expEncodes int
+	}{
+		{inm: `"v1"`, expCode: 304, expEncodes: 0},
+		{inm: `"v0"`, expCode: 200, expEncodes: 1},
+	} {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			var encodes int
+			r := httptest.NewRequest("GET", "/", nil)
+			r.Header.Set("If-None-Match", c.inm)
+
+			w := httptest.NewRecorder()
+			ep.New(
+				ep.ResponseEncoding(epcoding.JSON{}),
+				ep.ResponseHook(Conditional),
+				StatusHook,
+			).Handle(func() interface{} { return output10{&encodes} }).ServeHTTP(w, r)
+
+			if w.Code != c.expCode || encodes != c.expEncodes {
+				t.Fatalf("expected %d with %d encodes, got: %d with %d", c.expCode, c.expEncodes, w.Code, encodes)
+			}
+
+			if w.Header().Get("ETag") != `"v1"` {
+				t.Fatalf("unexpected etag, got: %v", w.Header())
+			}
+		})
+	}
+}
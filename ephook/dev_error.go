@@ -0,0 +1,159 @@
+package ephook
+
+import (
+	"encoding/xml"
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+
+	"github.com/advanderveer/ep"
+)
+
+// NewDevError creates an error hook that reveals everything there is to know
+// about an error: the full error chain with ops and kinds, the stack of any
+// recovered panic and details about the request. If the Codec is configured
+// with the ep.Debug option it also shows the negotiated encoder and decoder,
+// a preview of the request body and the hooks that ran. HTML clients get a
+// detailed error page while other encoders (e.g. JSON) receive the same
+// information as a structured output.
+//
+// The output reveals internals of the server and should therefore NEVER be
+// used outside of development.
+func NewDevError(logs *log.Logger) ep.RequestErrorHook {
+	return func(r *http.Request, ct string, err error) interface{} {
+		if logs != nil {
+			logs.Print(requestPrefix(r) + err.Error())
+		}
+
+		out := devErrorOutput{
+			StatusCode: http.StatusInternalServerError,
+			Message:    err.Error(),
+			Chain:      errorChain(err),
+			Stack:      string(ep.Stack(err)),
+		}
+
+		var eperr *ep.Error
+		if errors.As(err, &eperr) {
+			out.StatusCode = errorStatus(eperr)
+		}
+
+		if r != nil {
+			out.Request = &devErrorRequest{
+				Method: r.Method,
+				URL:    r.URL.String(),
+				Header: r.Header,
+			}
+
+			if di := ep.DebugInfoFromContext(r.Context()); di != nil {
+				out.Request.BodyPreview = string(di.BodyPreview())
+				out.Debug = &devErrorDebug{
+					Decoder:            di.Decoder,
+					Encoder:            di.Encoder,
+					EncoderContentType: di.EncoderContentType,
+					Hooks:              di.Hooks,
+				}
+			}
+		}
+
+		return out
+	}
+}
+
+// errorChain flattens the error's tree into a list, depth-first
+func errorChain(err error) (chain []devErrorLink) {
+	if err == nil {
+		return
+	}
+
+	link := devErrorLink{Message: err.Error()}
+	if eperr, ok := err.(*ep.Error); ok {
+		link.Op, link.Kind, link.Message = string(eperr.Op()), eperr.Kind().String(), eperr.Message()
+	}
+
+	chain = append(chain, link)
+	switch et := err.(type) {
+	case interface{ Unwrap() error }:
+		chain = append(chain, errorChain(et.Unwrap())...)
+	case interface{ Unwrap() []error }:
+		for _, err := range et.Unwrap() {
+			chain = append(chain, errorChain(err)...)
+		}
+	}
+
+	return
+}
+
+var devErrorTemplate = template.Must(template.New("").Parse(`<!doctype html>
+<html lang="en">
+<head>
+<title>{{.StatusCode}}: {{.Message}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+pre { background: #f4f4f4; padding: 1em; overflow: auto; }
+th { text-align: left; padding-right: 1em; vertical-align: top; }
+</style>
+</head>
+<body>
+<h1>{{.StatusCode}}: {{.Message}}</h1>
+<h2>Error chain</h2>
+<table>
+<tr><th>Op</th><th>Kind</th><th>Message</th></tr>
+{{range .Chain}}<tr><td>{{.Op}}</td><td>{{.Kind}}</td><td>{{.Message}}</td></tr>
+{{end}}</table>
+{{if .Stack}}<h2>Stack</h2>
+<pre>{{.Stack}}</pre>
+{{end}}{{with .Request}}<h2>Request</h2>
+<pre>{{.Method}} {{.URL}}</pre>
+<table>
+{{range $k, $v := .Header}}<tr><th>{{$k}}</th><td>{{range $v}}{{.}} {{end}}</td></tr>
+{{end}}</table>
+{{if .BodyPreview}}<pre>{{.BodyPreview}}</pre>
+{{end}}{{end}}{{with .Debug}}<h2>Handling</h2>
+<table>
+<tr><th>Decoder</th><td>{{.Decoder}}</td></tr>
+<tr><th>Encoder</th><td>{{.Encoder}} ({{.EncoderContentType}})</td></tr>
+</table>
+<ol>
+{{range .Hooks}}<li>{{.}}</li>
+{{end}}</ol>
+{{end}}</body>
+</html>`))
+
+type (
+	devErrorOutput struct {
+		XMLName    xml.Name         `json:"-" xml:"Error"`
+		StatusCode int              `json:"status"`
+		Message    string           `json:"message"`
+		Chain      []devErrorLink   `json:"chain"`
+		Stack      string           `json:"stack,omitempty" xml:",omitempty"`
+		Request    *devErrorRequest `json:"request,omitempty" xml:",omitempty"`
+		Debug      *devErrorDebug   `json:"debug,omitempty" xml:",omitempty"`
+	}
+
+	devErrorLink struct {
+		Op      string `json:"op,omitempty" xml:",omitempty"`
+		Kind    string `json:"kind,omitempty" xml:",omitempty"`
+		Message string `json:"message"`
+	}
+
+	devErrorRequest struct {
+		Method      string      `json:"method"`
+		URL         string      `json:"url"`
+		Header      http.Header `json:"header" xml:"-"`
+		BodyPreview string      `json:"body_preview,omitempty" xml:",omitempty"`
+	}
+
+	devErrorDebug struct {
+		Decoder            string   `json:"decoder,omitempty" xml:",omitempty"`
+		Encoder            string   `json:"encoder,omitempty" xml:",omitempty"`
+		EncoderContentType string   `json:"encoder_content_type,omitempty" xml:",omitempty"`
+		Hooks              []string `json:"hooks" xml:"Hook"`
+	}
+)
+
+func (out devErrorOutput) Status() int { return out.StatusCode }
+
+func (out devErrorOutput) Template() *template.Template {
+	return devErrorTemplate
+}
@@ -0,0 +1,109 @@
+package ephook
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/advanderveer/ep"
+	"github.com/advanderveer/ep/epcoding"
+)
+
+func TestDevErrorOutput(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	r := httptest.NewRequest("GET", "/foo", nil)
+	err := ep.Err(ep.Op("a.b"), "failed", ep.NotFound("secret"))
+
+	out := NewDevError(log.New(buf, "", 0))(r, "application/json", err).(devErrorOutput)
+	if out.Status() != 404 {
+		t.Fatalf("unexpected status, got: %v", out.Status())
+	}
+
+	if buf.String() != "GET /foo: a.b: failed: secret\n" {
+		t.Fatalf("should have logged, got: %v", buf.String())
+	}
+
+	if len(out.Chain) != 2 ||
+		out.Chain[0].Op != "a.b" || out.Chain[0].Kind != "not found" || out.Chain[0].Message != "failed" ||
+		out.Chain[1].Kind != "not found" || out.Chain[1].Message != "secret" {
+		t.Fatalf("unexpected chain, got: %+v", out.Chain)
+	}
+
+	if out.Request == nil || out.Request.Method != "GET" || out.Request.URL != "/foo" {
+		t.Fatalf("unexpected request, got: %+v", out.Request)
+	}
+
+	if out.Debug != nil {
+		t.Fatalf("should not have debug info without the Debug option")
+	}
+
+	out = NewDevError(nil)(nil, "", errors.Join(errors.New("foo"), errors.New("bar"))).(devErrorOutput)
+	if out.Status() != 500 || len(out.Chain) != 3 || out.Chain[2].Message != "bar" {
+		t.Fatalf("unexpected output, got: %+v", out)
+	}
+}
+
+func TestDevErrorWithCodec(t *testing.T) {
+	type input struct{ Name string }
+
+	h := ep.New(
+		ep.Debug(),
+		ep.RequestDecoding(epcoding.JSON{}),
+		ep.ResponseEncoding(epcoding.JSON{}),
+		ep.ResponseEncoding(epcoding.NewHTML(nil)),
+		StatusHook,
+		NewDevError(nil),
+	).Handle(func(in *input) error {
+		panic("boom")
+	})
+
+	r := httptest.NewRequest("POST", "/", strings.NewReader(`{"Name":"foo"}`))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("Accept", "application/json")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("unexpected status, got: %v", w.Code)
+	}
+
+	var out struct {
+		Message string
+		Stack   string
+		Request struct {
+			BodyPreview string `json:"body_preview"`
+		}
+		Debug struct {
+			Decoder, Encoder string
+			Hooks            []string
+		}
+	}
+
+	if err := json.NewDecoder(w.Body).Decode(&out); err != nil {
+		t.Fatalf("unexpected, got: %v", err)
+	}
+
+	if out.Message != "boom" || !strings.Contains(out.Stack, "goroutine") {
+		t.Fatalf("unexpected error output, got: %+v", out)
+	}
+
+	if out.Request.BodyPreview != `{"Name":"foo"}` || out.Debug.Decoder != "*json.Decoder" || len(out.Debug.Hooks) != 1 {
+		t.Fatalf("unexpected debug output, got: %+v", out)
+	}
+
+	r = httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Accept", "text/html")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if w.Code != http.StatusInternalServerError ||
+		!strings.Contains(w.Body.String(), "<h1>500: boom</h1>") ||
+		!strings.Contains(w.Body.String(), "<h2>Stack</h2>") {
+		t.Fatalf("unexpected html page, got: %v", w.Body.String())
+	}
+}
@@ -1,10 +1,12 @@
 package ephook
 
 import (
+	"context"
 	"encoding/xml"
 	"errors"
 	"html/template"
 	"log"
+	"log/slog"
 	"net/http"
 
 	"github.com/advanderveer/ep"
@@ -12,44 +14,177 @@ import (
 
 // NewStandardError creates an error hook for handling ep.Error errors. It logs
 // errors to the provided logger creates sensible status codes and only revels
-// standard HTTP text that is associated with that code. It comes with default
-// outputs for the XML, JSON and HTML encoders.
+// standard HTTP text that is associated with that code, unless the error
+// carries a public message. The error's code and details are also included.
+// It comes with default outputs for the XML, JSON and HTML encoders.
 func NewStandardError(logs *log.Logger) func(err error) interface{} {
 	return func(err error) interface{} {
 		if logs != nil {
 			logs.Print(err)
 		}
 
-		// we only create outputs for ep.Error types
-		var eperr *ep.Error
-		if !errors.As(err, &eperr) {
-			return nil
+		return standardErrorOutput(nil, err)
+	}
+}
+
+// NewRequestStandardError creates a request-aware variant of the
+// NewStandardError hook. The method, path and request ID (if the request has
+// one) are logged together with the error. The request ID is also included
+// in the output so clients can quote it when asking for support.
+func NewRequestStandardError(logs *log.Logger) ep.RequestErrorHook {
+	return func(r *http.Request, ct string, err error) interface{} {
+		if logs != nil {
+			logs.Print(requestPrefix(r) + err.Error())
+		}
+
+		return standardErrorOutput(r, err)
+	}
+}
+
+// NewStructuredError creates an error hook that renders the same outputs as
+// NewRequestStandardError but logs errors with structured attributes of the request
+// and the error. Server errors are logged at the error level, others at the
+// info level. If 'logs' is nil the request-scoped logger that is provided by
+// ep.LoggerFromContext is used.
+func NewStructuredError(logs *slog.Logger) ep.RequestErrorHook {
+	return func(r *http.Request, ct string, err error) interface{} {
+		out := standardErrorOutput(r, err)
+
+		var attrs []any
+		ctx, elogs := context.Background(), logs
+		if r != nil {
+			ctx = r.Context()
 		}
 
-		out := errorOutput{status: http.StatusInternalServerError}
 		switch {
-		case errors.Is(eperr, ep.Err(ep.UnacceptableError)):
-			out.status = http.StatusNotAcceptable
-		case errors.Is(eperr, ep.Err(ep.UnsupportedError)):
-			out.status = http.StatusUnsupportedMediaType
-		case errors.Is(eperr, ep.Err(ep.DecoderError)):
-			out.status = http.StatusBadRequest
+		case elogs == nil:
+			elogs = ep.LoggerFromContext(ctx)
+		case r != nil:
+			attrs = append(attrs, ep.RequestAttrs(r)...)
 		}
 
-		out.Message = http.StatusText(out.status)
+		status, level := http.StatusInternalServerError, slog.LevelError
+		if out != nil {
+			status = out.(errorOutput).status
+		}
+
+		if status < 500 {
+			level = slog.LevelInfo
+		}
+
+		attrs = append(attrs, ep.ErrorAttrs(err)...)
+		attrs = append(attrs, slog.Int("status", status), slog.String("content_type", ct))
+		elogs.Log(ctx, level, "failed to handle request", attrs...)
 		return out
 	}
 }
 
+// standardErrorOutput creates the output for ep.Error errors
+func standardErrorOutput(r *http.Request, err error) interface{} {
+
+	// we only create outputs for ep.Error types
+	var eperr *ep.Error
+	if !errors.As(err, &eperr) {
+		return nil
+	}
+
+	out := errorOutput{status: errorStatus(eperr)}
+
+	// only messages that are explicitely marked as public are revealed
+	out.Message = ep.Public(eperr)
+	if out.Message == "" {
+		out.Message = http.StatusText(out.status)
+	}
+
+	out.Code = ep.Code(eperr)
+	out.Details = ep.Details(eperr)
+	if r != nil {
+		out.RequestID = ep.RequestIDFromContext(r.Context())
+	}
+
+	return out
+}
+
+// errorStatus determines the HTTP status code for the ep.Error from its
+// outermost kind, such that wrapping an error can change its status.
+func errorStatus(eperr *ep.Error) int {
+	switch statusKind(eperr) {
+	case ep.UnacceptableError:
+		return http.StatusNotAcceptable
+	case ep.UnsupportedError:
+		return http.StatusUnsupportedMediaType
+	case ep.DecoderError:
+		return http.StatusBadRequest
+	case ep.PreconditionFailedError:
+		return http.StatusPreconditionFailed
+	case ep.PreconditionRequiredError:
+		return http.StatusPreconditionRequired
+	case ep.NotFoundError:
+		return http.StatusNotFound
+	case ep.ConflictError:
+		return http.StatusConflict
+	case ep.UnauthorizedError:
+		return http.StatusUnauthorized
+	case ep.ForbiddenError:
+		return http.StatusForbidden
+	case ep.InvalidError:
+		return http.StatusUnprocessableEntity
+	case ep.RateLimitedError:
+		return http.StatusTooManyRequests
+	case ep.UnavailableError:
+		return http.StatusServiceUnavailable
+	case ep.TimeoutError:
+		return http.StatusGatewayTimeout
+	case ep.MethodNotAllowedError:
+		return http.StatusMethodNotAllowed
+	}
+
+	return http.StatusInternalServerError
+}
+
+// statusKind returns the outermost kind of the error. Request hook errors only
+// wrap the error returned by the hook, so the kind of that error is used.
+func statusKind(err error) ep.ErrorKind {
+	kind := ep.Kind(err)
+	if kind != ep.RequestHookError {
+		return kind
+	}
+
+	for ; err != nil; err = errors.Unwrap(err) {
+		if eperr, ok := err.(*ep.Error); ok && eperr.Kind() == ep.RequestHookError {
+			return statusKind(eperr.Unwrap())
+		}
+	}
+
+	return kind
+}
+
+// requestPrefix describes the request for logging purposes
+func requestPrefix(r *http.Request) string {
+	if r == nil {
+		return ""
+	}
+
+	prefix := r.Method + " " + r.URL.Path
+	if rid := ep.RequestIDFromContext(r.Context()); rid != "" {
+		prefix += " (" + rid + ")"
+	}
+
+	return prefix + ": "
+}
+
 var errorTemplate = template.Must(template.New("").Parse(
-	`<!doctype html><html lang="en"><head><title>{{.Message}}</title></head><body>{{.Message}}</body></html>`,
+	`<!doctype html><html lang="en"><head><title>{{.Message}}</title></head><body>{{.Message}}{{with .RequestID}}<p>Request ID: {{.}}</p>{{end}}</body></html>`,
 ))
 
 type errorOutput struct {
 	status int
 
-	Message string   `json:"message"`
-	XMLName xml.Name `json:"-" xml:"Error"`
+	Message   string          `json:"message"`
+	Code      string          `json:"code,omitempty" xml:",omitempty"`
+	Details   ep.ErrorDetails `json:"details,omitempty" xml:"-"`
+	RequestID string          `json:"request_id,omitempty" xml:",omitempty"`
+	XMLName   xml.Name        `json:"-" xml:"Error"`
 }
 
 func (out errorOutput) Status() int { return out.status }
@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"errors"
 	"log"
+	"log/slog"
+	"net/http"
 	"net/http/httptest"
 	"strconv"
 	"testing"
@@ -17,10 +19,31 @@ func TestPrivateErrorLogs(t *testing.T) {
 	logs := log.New(buf, "", 0)
 
 	NewStandardError(logs)(errors.New("foo"))
+	if buf.String() != "foo\n" {
+		t.Fatalf("should have logged, got: %v", buf.String())
+	}
 
+	buf.Reset()
+	NewRequestStandardError(logs)(nil, "", errors.New("foo"))
 	if buf.String() != "foo\n" {
 		t.Fatalf("should have logged, got: %v", buf.String())
 	}
+
+	buf.Reset()
+	r := withRequestID(httptest.NewRequest("DELETE", "/foo?bar=1", nil), "abc")
+	NewRequestStandardError(logs)(r, "application/json", errors.New("foo"))
+	if buf.String() != "DELETE /foo (abc): foo\n" {
+		t.Fatalf("should have logged with request, got: %v", buf.String())
+	}
+
+	// the ID is only logged if the Codec assigned it
+	buf.Reset()
+	r = httptest.NewRequest("DELETE", "/foo", nil)
+	r.Header.Set("X-Request-ID", "abc\ndef")
+	NewRequestStandardError(logs)(r, "application/json", errors.New("foo"))
+	if buf.String() != "DELETE /foo: foo\n" {
+		t.Fatalf("should have logged without request id, got: %v", buf.String())
+	}
 }
 
 func TestPrivateErrorWithResponseHookAndEncoding(t *testing.T) {
@@ -38,6 +61,25 @@ func TestPrivateErrorWithResponseHookAndEncoding(t *testing.T) {
 		{epcoding.JSON{}, ep.Err(ep.DecoderError), 400, `{"message":"Bad Request"}` + "\n"},
 		{epcoding.JSON{}, ep.Err(ep.UnsupportedError), 415, `{"message":"Unsupported Media Type"}` + "\n"},
 		{epcoding.JSON{}, ep.Err(ep.UnacceptableError), 406, `{"message":"Not Acceptable"}` + "\n"},
+		{epcoding.JSON{}, ep.Err(ep.PreconditionFailedError), 412, `{"message":"Precondition Failed"}` + "\n"},
+		{epcoding.JSON{}, ep.Err(ep.Err(ep.PreconditionRequiredError), ep.RequestHookError), 428, `{"message":"Precondition Required"}` + "\n"},
+		{epcoding.JSON{}, ep.NotFound("secret"), 404, `{"message":"Not Found"}` + "\n"},
+		{epcoding.JSON{}, ep.Conflict(), 409, `{"message":"Conflict"}` + "\n"},
+		{epcoding.JSON{}, ep.Unauthorized(), 401, `{"message":"Unauthorized"}` + "\n"},
+		{epcoding.JSON{}, ep.Forbidden(), 403, `{"message":"Forbidden"}` + "\n"},
+		{epcoding.JSON{}, ep.Invalid(errors.New("secret")), 422, `{"message":"Unprocessable Entity"}` + "\n"},
+		{epcoding.JSON{}, ep.RateLimited(), 429, `{"message":"Too Many Requests"}` + "\n"},
+		{epcoding.JSON{}, ep.Unavailable(), 503, `{"message":"Service Unavailable"}` + "\n"},
+		{epcoding.JSON{}, ep.Timeout(), 504, `{"message":"Gateway Timeout"}` + "\n"},
+		{epcoding.JSON{}, ep.Err(ep.MethodNotAllowedError), 405, `{"message":"Method Not Allowed"}` + "\n"},
+		{epcoding.JSON{}, ep.NotFound(ep.Op("op"), ep.Err(ep.DecoderError)), 404, `{"message":"Not Found"}` + "\n"},
+		{epcoding.JSON{}, ep.Err(ep.Op("op"), ep.ServerError, ep.Conflict()), 500, `{"message":"Internal Server Error"}` + "\n"},
+		{epcoding.JSON{}, ep.Err(ep.Op("op"), ep.RequestHookError, ep.NotFound(ep.Err(ep.DecoderError))), 404, `{"message":"Not Found"}` + "\n"},
+		{epcoding.JSON{}, ep.Err(ep.RequestHookError, errors.New("foo")), 500, `{"message":"Internal Server Error"}` + "\n"},
+		{epcoding.JSON{}, ep.Invalid("secret", ep.PublicMsg("name must not be empty")), 422, `{"message":"name must not be empty"}` + "\n"},
+		{epcoding.JSON{}, ep.Err(ep.Conflict(ep.PublicMsg("already exists"), ep.ErrorCode("exists"))), 409, `{"message":"already exists","code":"exists"}` + "\n"},
+		{epcoding.JSON{}, ep.Invalid(ep.ErrorDetails{"name": "empty"}), 422, `{"message":"Unprocessable Entity","details":{"name":"empty"}}` + "\n"},
+		{epcoding.XML{}, ep.Invalid(ep.ErrorCode("invalid"), ep.ErrorDetails{"name": "empty"}), 422, `<Error><Message>Unprocessable Entity</Message><Code>invalid</Code></Error>`},
 		{epcoding.XML{}, ep.Err(ep.UnacceptableError), 406, `<Error><Message>Not Acceptable</Message></Error>`},
 		{epcoding.NewHTML(nil), ep.Err(ep.UnacceptableError), 406, `<!doctype html><html lang="en"><head><title>Not Acceptable</title></head><body>Not Acceptable</body></html>`},
 	} {
@@ -62,3 +104,73 @@ func TestPrivateErrorWithResponseHookAndEncoding(t *testing.T) {
 		})
 	}
 }
+
+func TestStandardErrorRequestID(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	h := ep.New(
+		ep.RequestID(ep.RequestIDConfig{Generate: func() string { return "abc" }}),
+		ep.ResponseEncoding(epcoding.JSON{}),
+		ep.ResponseEncoding(epcoding.NewHTML(nil)),
+		StatusHook,
+		NewRequestStandardError(log.New(buf, "", 0)),
+	).Handle(func() error { return ep.NotFound("foo") })
+
+	r := httptest.NewRequest("GET", "/bar", nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if w.Code != 404 || w.Header().Get("X-Request-ID") != "abc" ||
+		w.Body.String() != `{"message":"Not Found","request_id":"abc"}`+"\n" {
+		t.Fatalf("unexpected response, got: %v %v %v", w.Code, w.Header(), w.Body.String())
+	}
+
+	if buf.String() != "GET /bar (abc): foo\n" {
+		t.Fatalf("should have logged with request id, got: %v", buf.String())
+	}
+
+	r = httptest.NewRequest("GET", "/bar", nil)
+	r.Header.Set("Accept", "text/html")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if w.Body.String() != `<!doctype html><html lang="en"><head><title>Not Found</title></head><body>Not Found<p>Request ID: abc</p></body></html>` {
+		t.Fatalf("unexpected html, got: %v", w.Body.String())
+	}
+}
+
+func TestStructuredErrorLogs(t *testing.T) {
+	for i, c := range []struct {
+		err    error
+		expLog string
+	}{
+		{errors.New("foo"), "level=ERROR msg=\"failed to handle request\" method=DELETE path=/foo request_id=abc error=foo status=500 content_type=application/json\n"},
+		{ep.NotFound(ep.Op("a.b"), "bar"), "level=INFO msg=\"failed to handle request\" method=DELETE path=/foo request_id=abc error=bar op=a.b kind=\"not found\" status=404 content_type=application/json\n"},
+	} {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			buf := bytes.NewBuffer(nil)
+			logs := slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{
+				ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+					if a.Key == slog.TimeKey {
+						return slog.Attr{}
+					}
+
+					return a
+				},
+			}))
+
+			r := withRequestID(httptest.NewRequest("DELETE", "/foo", nil), "abc")
+			NewStructuredError(logs)(r, "application/json", c.err)
+			if buf.String() != c.expLog {
+				t.Fatalf("unexpected log, got: %v", buf.String())
+			}
+		})
+	}
+}
+
+// withRequestID returns the request as the Codec provides it to handlers when
+// it assigned it the ID 'rid'
+func withRequestID(r *http.Request, rid string) (rr *http.Request) {
+	ep.New(ep.RequestID(ep.RequestIDConfig{IgnoreIncoming: true, Generate: func() string { return rid }})).
+		Handle(func(r *http.Request) { rr = r }).ServeHTTP(httptest.NewRecorder(), r)
+	return
+}
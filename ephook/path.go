@@ -0,0 +1,216 @@
+package ephook
+
+import (
+	"encoding"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/advanderveer/ep"
+)
+
+// PathParamsConfig configures the path parameters hook
+type PathParamsConfig struct {
+
+	// Extract returns the value of the named path parameter. It defaults to
+	// http.Request.PathValue which provides the wildcards of ServeMux patterns.
+	// Other routers can be supported by providing their equivalent.
+	Extract func(r *http.Request, name string) string
+
+	// InvalidKind is the kind of error that is returned when a value cannot be
+	// converted to the field's type. It defaults to ep.NotFoundError, since a
+	// malformed path usually means the resource doesn't exist. Use
+	// ep.DecoderError to respond with 400 Bad Request instead.
+	InvalidKind ep.ErrorKind
+}
+
+// PathParams is a decoded hook that binds path parameters with the default
+// configuration, see NewPathParams.
+var PathParams = NewPathParams(PathParamsConfig{})
+
+// NewPathParams creates a decoded hook that binds path parameters into the
+// fields of (pointer) struct inputs that are tagged with `path:"name"`. Fields
+// can be strings, bools, ints, uints, floats or any type that implements
+// encoding.TextUnmarshaler. With `path:"name,uuid"` a string field only
+// accepts a UUID, which is normalized to lower case. Fields of parameters
+// that are empty are left untouched.
+//
+// The hook is called after the body was decoded such that the client cannot
+// retarget the request by providing the same field in the body. Tagged fields
+// of an unsupported type cause a server error.
+func NewPathParams(cfg PathParamsConfig) ep.DecodedHook {
+	if cfg.Extract == nil {
+		cfg.Extract = func(r *http.Request, name string) string { return r.PathValue(name) }
+	}
+
+	if cfg.InvalidKind == ep.OtherError {
+		cfg.InvalidKind = ep.NotFoundError
+	}
+
+	return func(r *http.Request, in interface{}) error {
+		const op ep.Op = "ephook.PathParams"
+
+		rv := reflect.ValueOf(in)
+		if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+			return nil
+		}
+
+		rv = rv.Elem()
+		pfs, err := pathFieldsOf(rv.Type())
+		if err != nil {
+			return ep.Err(op, err, ep.ServerError)
+		}
+
+		for _, pf := range pfs {
+			v := cfg.Extract(r, pf.name)
+			if v == "" {
+				continue
+			}
+
+			fv, err := rv.FieldByIndexErr(pf.index)
+			if err != nil {
+				continue // embedded through a nil pointer
+			}
+
+			if err = setPathValue(fv, v, pf.uuid); err != nil {
+				return ep.Err(op, "invalid path parameter '"+pf.name+"'", err, cfg.InvalidKind)
+			}
+		}
+
+		return nil
+	}
+}
+
+// pathField describes a struct field that is bound to a path parameter
+type pathField struct {
+	index []int
+	name  string
+	uuid  bool
+}
+
+// pathFields holds the result of analyzing a struct type
+type pathFields struct {
+	fields []pathField
+	err    error
+}
+
+var pathFieldsCache sync.Map // reflect.Type -> pathFields
+
+// pathFieldsOf returns the tagged fields of the struct type 't', or an error
+// if a field has an unsupported type. The result is cached per type.
+func pathFieldsOf(t reflect.Type) ([]pathField, error) {
+	if pfs, ok := pathFieldsCache.Load(t); ok {
+		return pfs.(pathFields).fields, pfs.(pathFields).err
+	}
+
+	var pfs pathFields
+	for _, f := range reflect.VisibleFields(t) {
+		tag, ok := f.Tag.Lookup("path")
+		if !ok || !f.IsExported() {
+			continue
+		}
+
+		if !supportedPathType(f.Type) {
+			pfs = pathFields{err: ep.Err("unsupported type for path parameter field " + f.Name + ": " + f.Type.String())}
+			break
+		}
+
+		name, opt, _ := strings.Cut(tag, ",")
+		pfs.fields = append(pfs.fields, pathField{index: f.Index, name: name, uuid: opt == "uuid"})
+	}
+
+	pathFieldsCache.Store(t, pfs)
+	return pfs.fields, pfs.err
+}
+
+var textUnmarshalerTyp = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// supportedPathType returns whether path values can be converted to type 't'
+func supportedPathType(t reflect.Type) bool {
+	if reflect.PointerTo(t).Implements(textUnmarshalerTyp) {
+		return true
+	}
+
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+
+	return false
+}
+
+// setPathValue converts the value 'v' to the type of field 'fv' and sets it
+func setPathValue(fv reflect.Value, v string, uuid bool) error {
+	if fv.Addr().Type().Implements(textUnmarshalerTyp) {
+		return fv.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(v))
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		if uuid {
+			if !isUUID(v) {
+				return ep.Err("not a uuid")
+			}
+
+			v = strings.ToLower(v)
+		}
+
+		fv.SetString(v)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return err
+		}
+
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(v, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		fv.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(v, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		fv.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(v, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		fv.SetFloat(f)
+	}
+
+	return nil
+}
+
+// isUUID checks if 'v' is formatted as a UUID
+func isUUID(v string) bool {
+	if len(v) != 36 {
+		return false
+	}
+
+	for i := 0; i < len(v); i++ {
+		switch c := v[i]; {
+		case i == 8 || i == 13 || i == 18 || i == 23:
+			if c != '-' {
+				return false
+			}
+		case c >= '0' && c <= '9', c >= 'a' && c <= 'f', c >= 'A' && c <= 'F':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,159 @@
+package ephook
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/advanderveer/ep"
+	"github.com/advanderveer/ep/epcoding"
+)
+
+type pathEmbed struct {
+	Page uint8 `path:"page"`
+}
+
+type pathInput struct {
+	pathEmbed
+	ID    string    `path:"id,uuid"`
+	Num   int64     `path:"num"`
+	Ratio float32   `path:"ratio"`
+	On    bool      `path:"on"`
+	At    time.Time `path:"at"`
+	Name  string    `path:"name"`
+	Other string
+}
+
+func TestPathParams(t *testing.T) {
+	for i, c := range []struct {
+		params  map[string]string
+		cfg     PathParamsConfig
+		expIn   pathInput
+		expKind ep.ErrorKind
+	}{
+		{params: map[string]string{}},
+		{
+			params: map[string]string{
+				"id": "6BA7B810-9DAD-11D1-80B4-00C04FD430C8", "num": "-42", "ratio": "0.5",
+				"on": "true", "at": "2020-01-02T03:04:05Z", "name": "foo", "page": "3",
+			},
+			expIn: pathInput{
+				pathEmbed: pathEmbed{Page: 3},
+				ID:        "6ba7b810-9dad-11d1-80b4-00c04fd430c8", Num: -42, Ratio: 0.5,
+				On: true, At: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC), Name: "foo",
+			},
+		},
+		{params: map[string]string{"id": "foo"}, expKind: ep.NotFoundError},
+		{params: map[string]string{"num": "1.5"}, expKind: ep.NotFoundError},
+		{params: map[string]string{"page": "256"}, expKind: ep.NotFoundError},
+		{params: map[string]string{"at": "yesterday"}, expKind: ep.NotFoundError},
+		{params: map[string]string{"on": "maybe"}, cfg: PathParamsConfig{InvalidKind: ep.DecoderError}, expKind: ep.DecoderError},
+	} {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			c.cfg.Extract = func(r *http.Request, name string) string { return c.params[name] }
+
+			var in pathInput
+			err := NewPathParams(c.cfg)(httptest.NewRequest("GET", "/", nil), &in)
+			if c.expKind != ep.OtherError {
+				if ep.Kind(err) != c.expKind {
+					t.Fatalf("expected error kind %v, got: %v", c.expKind, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected, got: %v", err)
+			}
+
+			if in != c.expIn {
+				t.Fatalf("expected %+v, got: %+v", c.expIn, in)
+			}
+		})
+	}
+}
+
+func TestPathParamsIgnoresNonStructs(t *testing.T) {
+	var s string
+	for _, in := range []interface{}{nil, s, &s, pathInput{}, (*pathInput)(nil)} {
+		if err := PathParams(httptest.NewRequest("GET", "/", nil), in); err != nil {
+			t.Fatalf("unexpected, got: %v", err)
+		}
+	}
+}
+
+func TestPathParamsUnsupportedType(t *testing.T) {
+	for i := 0; i < 2; i++ {
+		err := PathParams(httptest.NewRequest("GET", "/", nil), &struct {
+			Foo []string `path:"foo"`
+		}{})
+		if ep.Kind(err) != ep.ServerError {
+			t.Fatalf("expected server error, got: %v", err)
+		}
+	}
+}
+
+func TestPathParamsOverrideBody(t *testing.T) {
+	type input struct {
+		ID   int `path:"id"`
+		Name string
+	}
+
+	rt := ep.NewRouter(ep.New(
+		ep.RequestDecoding(epcoding.JSON{}),
+		ep.ResponseEncoding(epcoding.JSON{}),
+		PathParams,
+	))
+
+	rt.Route("PUT", "/ideas/{id}", func(in *input) *input { return in })
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("PUT", "/ideas/5", strings.NewReader(`{"ID":99,"Name":"foo"}`))
+	r.Header.Set("Content-Type", "application/json")
+	rt.ServeHTTP(w, r)
+	if w.Code != 200 || w.Body.String() != `{"ID":5,"Name":"foo"}`+"\n" {
+		t.Fatalf("unexpected response, got: %v %v", w.Code, w.Body.String())
+	}
+}
+
+func TestPathParamsWithServeMux(t *testing.T) {
+	type input struct {
+		ID int `path:"id"`
+	}
+
+	rt := ep.NewRouter(ep.New(
+		ep.ResponseEncoding(epcoding.JSON{}),
+		PathParams,
+		StatusHook,
+		ep.ErrorHook(NewStandardError(nil)),
+	))
+
+	rt.Route("GET", "/ideas/{id}", func(in *input) (interface{}, error) {
+		if in.ID != 42 {
+			return nil, errors.New("unexpected id")
+		}
+
+		return in, nil
+	})
+
+	for i, c := range []struct {
+		path    string
+		expCode int
+		expBody string
+	}{
+		{"/ideas/42", 200, `{"ID":42}` + "\n"},
+		{"/ideas/foo", 404, `{"message":"Not Found"}` + "\n"},
+	} {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			w := httptest.NewRecorder()
+			rt.ServeHTTP(w, httptest.NewRequest("GET", c.path, nil))
+			if w.Code != c.expCode || w.Body.String() != c.expBody {
+				t.Fatalf("unexpected response, got: %v %v", w.Code, w.Body.String())
+			}
+		})
+	}
+}
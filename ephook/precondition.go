@@ -0,0 +1,106 @@
+package ephook
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/advanderveer/ep"
+)
+
+// Preconditions holds the values of the If-Match and If-Unmodified-Since
+// request headers. It can be embedded in inputs for the Precondition hook to
+// populate it.
+type Preconditions struct {
+	IfMatch           []string
+	IfUnmodifiedSince time.Time
+}
+
+// SetPreconditions is called by the Precondition hook
+func (p *Preconditions) SetPreconditions(pc Preconditions) { *p = pc }
+
+// empty returns whether the request didn't specify any preconditions
+func (p Preconditions) empty() bool {
+	return len(p.IfMatch) < 1 && p.IfUnmodifiedSince.IsZero()
+}
+
+// Check compares the preconditions against the current version of the
+// resource. An empty etag means the resource doesn't exist and a zero
+// modified time means it is unknown. It returns an ep.Error of the
+// PreconditionFailedError kind if the preconditions don't hold.
+func (p Preconditions) Check(etag string, modified time.Time) error {
+	const op ep.Op = "ephook.Preconditions.Check"
+
+	if len(p.IfMatch) > 0 {
+		for _, candidate := range p.IfMatch {
+			if candidate == "*" && etag != "" {
+				return nil
+			}
+
+			if strongMatch(candidate, quoteETag(etag)) {
+				return nil
+			}
+		}
+
+		return ep.Err(op, "resource does not match any of the entity tags", ep.PreconditionFailedError)
+	}
+
+	if !p.IfUnmodifiedSince.IsZero() && !modified.IsZero() &&
+		modified.Truncate(time.Second).After(p.IfUnmodifiedSince) {
+		return ep.Err(op, "resource was modified since", ep.PreconditionFailedError)
+	}
+
+	return nil
+}
+
+// Precondition is a request hook that reads the If-Match and
+// If-Unmodified-Since headers for inputs that have a SetPreconditions method,
+// for example by embedding the Preconditions type. If the input also has a
+// RequirePreconditions method that returns true, unsafe requests without any
+// preconditions fail with an error of the PreconditionRequiredError kind.
+func Precondition(r *http.Request, in interface{}) error {
+	const op ep.Op = "ephook.Precondition"
+
+	inn, ok := in.(interface{ SetPreconditions(Preconditions) })
+	if !ok {
+		return nil
+	}
+
+	var p Preconditions
+	for _, v := range r.Header.Values("If-Match") {
+		for _, candidate := range strings.Split(v, ",") {
+			if candidate = strings.TrimSpace(candidate); candidate != "" {
+				p.IfMatch = append(p.IfMatch, candidate)
+			}
+		}
+	}
+
+	if ius := r.Header.Get("If-Unmodified-Since"); ius != "" {
+		if t, err := http.ParseTime(ius); err == nil {
+			p.IfUnmodifiedSince = t
+		}
+	}
+
+	if inr, ok := in.(interface{ RequirePreconditions() bool }); ok &&
+		inr.RequirePreconditions() && p.empty() && !safeMethod(r.Method) {
+		return ep.Err(op, "request must be conditional", ep.PreconditionRequiredError)
+	}
+
+	inn.SetPreconditions(p)
+	return nil
+}
+
+// strongMatch compares two entity tags using the strong comparison function
+func strongMatch(a, b string) bool {
+	return !strings.HasPrefix(a, "W/") && !strings.HasPrefix(b, "W/") && a == b
+}
+
+// safeMethod returns whether the method is considered safe, see RFC 7231
+func safeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+
+	return false
+}
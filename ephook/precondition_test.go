@@ -0,0 +1,98 @@
+package ephook
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/advanderveer/ep"
+)
+
+type input4 struct {
+	Preconditions
+	Foo string
+}
+
+type input5 struct{ Preconditions }
+
+func (_ input5) RequirePreconditions() bool { return true }
+
+func TestPreconditionHook(t *testing.T) {
+	for i, c := range []struct {
+		method string
+		header http.Header
+		in     interface{}
+		expErr error
+		expIn  interface{}
+	}{
+		{method: "PUT", in: nil},
+		{method: "PUT", in: &struct{}{}, expIn: &struct{}{}},
+		{
+			method: "PUT", in: &input4{},
+			header: http.Header{"If-Match": {`"a", W/"b"`, `"c"`}},
+			expIn:  &input4{Preconditions: Preconditions{IfMatch: []string{`"a"`, `W/"b"`, `"c"`}}},
+		},
+		{
+			method: "DELETE", in: &input4{},
+			header: http.Header{"If-Unmodified-Since": {lastModified.Format(http.TimeFormat)}},
+			expIn:  &input4{Preconditions: Preconditions{IfUnmodifiedSince: lastModified}},
+		},
+		{
+			method: "PATCH", in: &input5{},
+			expErr: ep.Err(ep.Op("ephook.Precondition"), ep.PreconditionRequiredError),
+			expIn:  &input5{},
+		},
+		{ // safe methods don't require preconditions
+			method: "GET", in: &input5{},
+			expIn: &input5{},
+		},
+	} {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			r := httptest.NewRequest(c.method, "/", nil)
+			for k, v := range c.header {
+				r.Header[k] = v
+			}
+
+			err := Precondition(r, c.in)
+			if !errors.Is(err, c.expErr) {
+				t.Fatalf("expected error %#v, got: %#v", c.expErr, err)
+			}
+
+			if !reflect.DeepEqual(c.in, c.expIn) {
+				t.Fatalf("expected %#v, got: %#v", c.expIn, c.in)
+			}
+		})
+	}
+}
+
+func TestPreconditionsCheck(t *testing.T) {
+	failed := ep.Err(ep.PreconditionFailedError)
+
+	for i, c := range []struct {
+		p        Preconditions
+		etag     string
+		modified time.Time
+		expErr   error
+	}{
+		{Preconditions{}, "", time.Time{}, nil},
+		{Preconditions{IfMatch: []string{"*"}}, "v1", time.Time{}, nil},
+		{Preconditions{IfMatch: []string{"*"}}, "", time.Time{}, failed},
+		{Preconditions{IfMatch: []string{`"v0"`, `"v1"`}}, "v1", time.Time{}, nil},
+		{Preconditions{IfMatch: []string{`"v0"`, `"v1"`}}, `"v1"`, time.Time{}, nil},
+		{Preconditions{IfMatch: []string{`"v0"`}}, "v1", time.Time{}, failed},
+		{Preconditions{IfMatch: []string{`W/"v1"`}}, "v1", time.Time{}, failed},
+		{Preconditions{IfUnmodifiedSince: lastModified}, "", lastModified, nil},
+		{Preconditions{IfUnmodifiedSince: lastModified}, "", lastModified.Add(time.Hour), failed},
+	} {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			err := c.p.Check(c.etag, c.modified)
+			if !errors.Is(err, c.expErr) {
+				t.Fatalf("expected error %#v, got: %#v", c.expErr, err)
+			}
+		})
+	}
+}
@@ -1,11 +1,19 @@
 package ephook
 
-import "net/http"
+import (
+	"net/http"
+
+	"github.com/advanderveer/ep"
+)
+
+// RedirectHook option configures the Redirect hook in the ep.RedirectPhase,
+// such that it is called before the Status hook.
+var RedirectHook = ep.PhasedResponseHook(ep.RedirectPhase, Redirect)
 
 // Redirect is a response hook will check if the output implements a Redirect
 // method to return a non-empty string as a location to redirect to. If the
 // output also implements status it is called to determine the status code to
-// use for redirection.
+// use for redirection. Configure it with the RedirectHook option.
 func Redirect(w http.ResponseWriter, r *http.Request, out interface{}) {
 	status := http.StatusSeeOther
 	if outt, ok := out.(statusOutput); ok {
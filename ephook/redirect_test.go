@@ -6,6 +6,9 @@ import (
 	"reflect"
 	"strconv"
 	"testing"
+
+	"github.com/advanderveer/ep"
+	"github.com/advanderveer/ep/epcoding"
 )
 
 type output5 struct{}
@@ -59,3 +62,13 @@ func TestRedirectHook(t *testing.T) {
 		})
 	}
 }
+
+func TestRedirectHookBeforeStatusHook(t *testing.T) {
+	w := httptest.NewRecorder()
+	ep.New(ep.ResponseEncoding(epcoding.JSON{}), StatusHook, RedirectHook).Handle(func() interface{} { return output5{} }).
+		ServeHTTP(w, httptest.NewRequest("GET", "/foo", nil))
+
+	if w.Code != 301 || w.Header().Get("Location") != "/" {
+		t.Fatalf("expected redirect, got: %v %v", w.Code, w.Header())
+	}
+}
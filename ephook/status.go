@@ -1,11 +1,20 @@
 package ephook
 
-import "net/http"
+import (
+	"net/http"
+
+	"github.com/advanderveer/ep"
+)
 
 type statusOutput interface{ Status() int }
 
+// StatusHook option configures the Status hook in the ep.StatusPhase, such
+// that hooks that set headers or redirect are called before it.
+var StatusHook = ep.PhasedResponseHook(ep.StatusPhase, Status)
+
 // Status is a response hook will assert if the out interface has a status
-// method and if it has, write the header with that status
+// method and if it has, write the header with that status. Configure it with
+// the StatusHook option.
 func Status(w http.ResponseWriter, r *http.Request, out interface{}) {
 	if outt, ok := out.(statusOutput); ok {
 		w.WriteHeader(outt.Status())
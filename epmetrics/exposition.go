@@ -0,0 +1,115 @@
+package epmetrics
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// histogram holds the observations of a single series
+type histogram struct {
+	counts []uint64 // per bucket, not cumulative
+	sum    float64
+	count  uint64
+}
+
+// observe the value 'v' in the histogram with labels 'l'
+func observe(hs map[string]*histogram, l string, buckets []float64, v float64) {
+	h, ok := hs[l]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(buckets))}
+		hs[l] = h
+	}
+
+	if i := sort.SearchFloat64s(buckets, v); i < len(buckets) {
+		h.counts[i]++
+	}
+
+	h.sum += v
+	h.count++
+}
+
+// labels formats label name and value pairs, values are escaped as required by
+// the text format.
+func labels(kvs ...string) string {
+	var b strings.Builder
+	for i := 0; i+1 < len(kvs); i += 2 {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+
+		b.WriteString(kvs[i])
+		b.WriteString(`="`)
+		b.WriteString(labelEscaper.Replace(kvs[i+1]))
+		b.WriteByte('"')
+	}
+
+	return b.String()
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// expositor writes metric families in the Prometheus text format
+type expositor struct{ strings.Builder }
+
+func (e *expositor) help(name, help, typ string) {
+	e.WriteString("# HELP " + name + " " + help + "\n")
+	e.WriteString("# TYPE " + name + " " + typ + "\n")
+}
+
+func (e *expositor) sample(name, labels string, v float64) {
+	e.WriteString(name)
+	if labels != "" {
+		e.WriteString("{" + labels + "}")
+	}
+
+	e.WriteString(" " + formatFloat(v) + "\n")
+}
+
+func (e *expositor) counter(name, help string, series map[string]float64) {
+	e.help(name, help, "counter")
+	for _, l := range sortedKeys(series) {
+		e.sample(name, l, series[l])
+	}
+}
+
+func (e *expositor) histogram(name, help string, buckets []float64, series map[string]*histogram) {
+	e.help(name, help, "histogram")
+	for _, l := range sortedKeys(series) {
+		h := series[l]
+
+		var cum uint64
+		for i, ub := range buckets {
+			cum += h.counts[i]
+			e.sample(name+"_bucket", l+`,le="`+formatFloat(ub)+`"`, float64(cum))
+		}
+
+		e.sample(name+"_bucket", l+`,le="+Inf"`, float64(h.count))
+		e.sample(name+"_sum", l, h.sum)
+		e.sample(name+"_count", l, float64(h.count))
+	}
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
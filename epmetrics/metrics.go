@@ -0,0 +1,161 @@
+// Package epmetrics records metrics of ep endpoints and exposes them in the
+// Prometheus text format, without depending on a Prometheus client library.
+package epmetrics
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/advanderveer/ep"
+)
+
+// DefaultDurationBuckets are the upper bounds (in seconds) of the request
+// duration histogram
+var DefaultDurationBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// DefaultSizeBuckets are the upper bounds (in bytes) of the response size
+// histogram
+var DefaultSizeBuckets = []float64{100, 1000, 10000, 100000, 1000000, 10000000}
+
+// UnknownEndpoint is the endpoint label of requests to handlers that were not
+// registered with Endpoint
+const UnknownEndpoint = "unknown"
+
+// OtherMethod is the method label of requests with a method that is not
+// standardized, such that clients cannot create an unbounded number of labels
+const OtherMethod = "other"
+
+// Metrics records request counts, latencies, response sizes, negotiation
+// failures and error kinds per endpoint and negotiated content type. It
+// records the outcome of requests by configuring its Observe method as an
+// ep.AfterHook and exposes the metrics by serving them as a http.Handler.
+type Metrics struct {
+	durationBuckets []float64
+	sizeBuckets     []float64
+
+	mu           sync.Mutex
+	endpoints    map[string]struct{}
+	requests     map[string]float64
+	durations    map[string]*histogram
+	sizes        map[string]*histogram
+	negotiations map[string]float64
+	errors       map[string]float64
+}
+
+// New initializes the metrics with the default buckets
+func New() *Metrics {
+	return NewWithBuckets(DefaultDurationBuckets, DefaultSizeBuckets)
+}
+
+// NewWithBuckets initializes the metrics with custom (sorted) histogram
+// buckets for request durations (in seconds) and response sizes (in bytes).
+func NewWithBuckets(durations, sizes []float64) *Metrics {
+	return &Metrics{
+		durationBuckets: durations,
+		sizeBuckets:     sizes,
+
+		endpoints:    make(map[string]struct{}),
+		requests:     make(map[string]float64),
+		durations:    make(map[string]*histogram),
+		sizes:        make(map[string]*histogram),
+		negotiations: make(map[string]float64),
+		errors:       make(map[string]float64),
+	}
+}
+
+type endpointKey struct{}
+
+// Endpoint registers the handler under the endpoint name such that requests it
+// handles are labeled with it. The handler is expected to be created by an ep
+// Codec that observes requests with the metrics.
+func (m *Metrics) Endpoint(name string, h http.Handler) http.Handler {
+	m.mu.Lock()
+	m.endpoints[name] = struct{}{}
+	m.mu.Unlock()
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), endpointKey{}, name)))
+	})
+}
+
+// Observe records the outcome of a request. It is meant to be configured as an
+// after hook: ep.AfterHook(m.Observe).
+func (m *Metrics) Observe(r *http.Request, o ep.Outcome) {
+	endpoint, _ := r.Context().Value(endpointKey{}).(string)
+	if endpoint == "" {
+		endpoint = UnknownEndpoint
+	}
+
+	ct := o.ContentType
+	if i := strings.IndexByte(ct, ';'); i >= 0 {
+		ct = strings.TrimSpace(ct[:i]) // parameters would only add cardinality
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.requests[labels(
+		"endpoint", endpoint,
+		"method", methodLabel(r.Method),
+		"status", strconv.Itoa(o.Status),
+		"content_type", ct,
+	)]++
+
+	el := labels("endpoint", endpoint, "content_type", ct)
+	observe(m.durations, el, m.durationBuckets, o.Duration.Seconds())
+	observe(m.sizes, el, m.sizeBuckets, float64(o.Written))
+
+	if o.Err == nil {
+		return
+	}
+
+	kind := ep.Kind(o.Err)
+	if kind == ep.UnacceptableError || kind == ep.UnsupportedError {
+		m.negotiations[labels("endpoint", endpoint, "kind", kind.String())]++
+	}
+
+	m.errors[labels("endpoint", endpoint, "kind", kind.String())]++
+}
+
+// methodLabel returns the method as a label if it is a standard method
+func methodLabel(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	}
+
+	return OtherMethod
+}
+
+// ServeHTTP writes the metrics in the Prometheus text exposition format
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var e expositor
+	e.counter("ep_requests_total", "Total number of requests handled.", m.requests)
+	e.histogram("ep_request_duration_seconds", "Duration of handling requests in seconds.", m.durationBuckets, m.durations)
+	e.histogram("ep_response_size_bytes", "Number of response body bytes written.", m.sizeBuckets, m.sizes)
+	e.counter("ep_negotiation_failures_total", "Total number of failures to negotiate an encoder or decoder.", m.negotiations)
+	e.counter("ep_errors_total", "Total number of requests that rendered an error, by error kind.", m.errors)
+
+	eps := make([]string, 0, len(m.endpoints))
+	for name := range m.endpoints {
+		eps = append(eps, labels("endpoint", name))
+	}
+
+	sort.Strings(eps)
+	e.help("ep_endpoint_info", "Endpoints that are registered for metrics.", "gauge")
+	for _, l := range eps {
+		e.sample("ep_endpoint_info", l, 1)
+	}
+
+	w.Write([]byte(e.String()))
+}
@@ -0,0 +1,107 @@
+package epmetrics
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/advanderveer/ep"
+	"github.com/advanderveer/ep/epcoding"
+)
+
+func TestExposition(t *testing.T) {
+	m := NewWithBuckets([]float64{0.1, 1}, []float64{10})
+	m.Endpoint(`list "ideas"`, http.NotFoundHandler())
+
+	r := httptest.NewRequest("GET", "/", nil)
+	m.Observe(r, ep.Outcome{Status: 200, Written: 5, Duration: 50 * time.Millisecond, ContentType: "application/json; charset=utf-8"})
+	m.Observe(r, ep.Outcome{Status: 406, Written: 20, Duration: 2 * time.Second, Err: ep.Err(ep.UnacceptableError)})
+	m.Observe(r, ep.Outcome{Status: 500, Duration: 500 * time.Millisecond, Err: errors.New("foo")})
+
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, r)
+
+	if w.Header().Get("Content-Type") != "text/plain; version=0.0.4; charset=utf-8" {
+		t.Fatalf("unexpected content type, got: %v", w.Header().Get("Content-Type"))
+	}
+
+	exp := `# HELP ep_requests_total Total number of requests handled.
+# TYPE ep_requests_total counter
+ep_requests_total{endpoint="unknown",method="GET",status="200",content_type="application/json"} 1
+ep_requests_total{endpoint="unknown",method="GET",status="406",content_type=""} 1
+ep_requests_total{endpoint="unknown",method="GET",status="500",content_type=""} 1
+# HELP ep_request_duration_seconds Duration of handling requests in seconds.
+# TYPE ep_request_duration_seconds histogram
+ep_request_duration_seconds_bucket{endpoint="unknown",content_type="",le="0.1"} 0
+ep_request_duration_seconds_bucket{endpoint="unknown",content_type="",le="1"} 1
+ep_request_duration_seconds_bucket{endpoint="unknown",content_type="",le="+Inf"} 2
+ep_request_duration_seconds_sum{endpoint="unknown",content_type=""} 2.5
+ep_request_duration_seconds_count{endpoint="unknown",content_type=""} 2
+ep_request_duration_seconds_bucket{endpoint="unknown",content_type="application/json",le="0.1"} 1
+ep_request_duration_seconds_bucket{endpoint="unknown",content_type="application/json",le="1"} 1
+ep_request_duration_seconds_bucket{endpoint="unknown",content_type="application/json",le="+Inf"} 1
+ep_request_duration_seconds_sum{endpoint="unknown",content_type="application/json"} 0.05
+ep_request_duration_seconds_count{endpoint="unknown",content_type="application/json"} 1
+# HELP ep_response_size_bytes Number of response body bytes written.
+# TYPE ep_response_size_bytes histogram
+ep_response_size_bytes_bucket{endpoint="unknown",content_type="",le="10"} 1
+ep_response_size_bytes_bucket{endpoint="unknown",content_type="",le="+Inf"} 2
+ep_response_size_bytes_sum{endpoint="unknown",content_type=""} 20
+ep_response_size_bytes_count{endpoint="unknown",content_type=""} 2
+ep_response_size_bytes_bucket{endpoint="unknown",content_type="application/json",le="10"} 1
+ep_response_size_bytes_bucket{endpoint="unknown",content_type="application/json",le="+Inf"} 1
+ep_response_size_bytes_sum{endpoint="unknown",content_type="application/json"} 5
+ep_response_size_bytes_count{endpoint="unknown",content_type="application/json"} 1
+# HELP ep_negotiation_failures_total Total number of failures to negotiate an encoder or decoder.
+# TYPE ep_negotiation_failures_total counter
+ep_negotiation_failures_total{endpoint="unknown",kind="unacceptable"} 1
+# HELP ep_errors_total Total number of requests that rendered an error, by error kind.
+# TYPE ep_errors_total counter
+ep_errors_total{endpoint="unknown",kind="other"} 1
+ep_errors_total{endpoint="unknown",kind="unacceptable"} 1
+# HELP ep_endpoint_info Endpoints that are registered for metrics.
+# TYPE ep_endpoint_info gauge
+ep_endpoint_info{endpoint="list \"ideas\""} 1
+`
+	if w.Body.String() != exp {
+		t.Fatalf("unexpected exposition, got:\n%v", w.Body.String())
+	}
+}
+
+func TestEndpointWithCodec(t *testing.T) {
+	m := New()
+	c := ep.New(
+		ep.ResponseEncoding(epcoding.JSON{}),
+		ep.AfterHook(m.Observe),
+	)
+
+	h := m.Endpoint("hello", c.Handle(func() interface{} { return "hello" }))
+	for i := 0; i < 2; i++ {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/", nil))
+	}
+
+	for _, method := range []string{"FOO", "BAR"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, "/", nil))
+	}
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Accept", "text/html")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, nil)
+
+	for _, exp := range []string{
+		`ep_requests_total{endpoint="hello",method="POST",status="200",content_type="application/json"} 2`,
+		`ep_requests_total{endpoint="hello",method="other",status="200",content_type="application/json"} 2`,
+		`ep_response_size_bytes_sum{endpoint="hello",content_type="application/json"} 32`,
+		`ep_negotiation_failures_total{endpoint="hello",kind="unacceptable"} 1`,
+	} {
+		if !strings.Contains(w.Body.String(), exp+"\n") {
+			t.Fatalf("expected %v, got:\n%v", exp, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,101 @@
+// Package eptrace provides tracing utilities for the ep package
+package eptrace
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/advanderveer/ep"
+)
+
+// Recorder is an ep.Tracer that keeps all spans in memory. It is meant for
+// testing the tracing of handlers and as an example of adapting a tracing
+// backend.
+type Recorder struct {
+	mu    sync.Mutex
+	spans []*RecordedSpan
+}
+
+// NewRecorder creates an empty recorder
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// Start a span as a child of the trace context in 'ctx'
+func (rec *Recorder) Start(ctx context.Context, name string) (context.Context, ep.Span) {
+	parent := ep.TraceContextFromContext(ctx)
+	span := &RecordedSpan{
+		Name:         name,
+		Parent:       parent,
+		TraceContext: parent.Child(),
+		Attributes:   make(map[string]interface{}),
+		StartTime:    time.Now(),
+		rec:          rec,
+	}
+
+	return ep.ContextWithTraceContext(ctx, span.TraceContext), span
+}
+
+// Spans returns the spans that have ended, in the order they ended
+func (rec *Recorder) Spans() []*RecordedSpan {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return append([]*RecordedSpan(nil), rec.spans...)
+}
+
+// Names returns the names of the spans that have ended, in the order they ended
+func (rec *Recorder) Names() (names []string) {
+	for _, s := range rec.Spans() {
+		names = append(names, s.Name)
+	}
+
+	return
+}
+
+// Reset removes all recorded spans
+func (rec *Recorder) Reset() {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	rec.spans = nil
+}
+
+// RecordedSpan is a span that was started by the Recorder
+type RecordedSpan struct {
+	Name         string
+	TraceContext ep.TraceContext
+	Parent       ep.TraceContext // zero value if the span is a root span
+	Attributes   map[string]interface{}
+	Errors       []error
+	StartTime    time.Time
+	EndTime      time.Time
+
+	rec   *Recorder
+	ended bool
+}
+
+// SetAttribute sets an attribute of the span
+func (s *RecordedSpan) SetAttribute(key string, value interface{}) {
+	s.rec.mu.Lock()
+	defer s.rec.mu.Unlock()
+	s.Attributes[key] = value
+}
+
+// RecordError records an error that occurred during the span
+func (s *RecordedSpan) RecordError(err error) {
+	s.rec.mu.Lock()
+	defer s.rec.mu.Unlock()
+	s.Errors = append(s.Errors, err)
+}
+
+// End the span, only the first call has any effect
+func (s *RecordedSpan) End() {
+	s.rec.mu.Lock()
+	defer s.rec.mu.Unlock()
+	if s.ended {
+		return
+	}
+
+	s.ended, s.EndTime = true, time.Now()
+	s.rec.spans = append(s.rec.spans, s)
+}
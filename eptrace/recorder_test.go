@@ -0,0 +1,89 @@
+package eptrace
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/advanderveer/ep"
+	"github.com/advanderveer/ep/epcoding"
+)
+
+func TestRecordCodecSpans(t *testing.T) {
+	rec := NewRecorder()
+	out := http.Header{}
+	h := ep.New(
+		ep.Tracing(rec),
+		ep.RequestDecoding(epcoding.JSON{}),
+		ep.ResponseEncoding(epcoding.JSON{}),
+	).Handle(func(ctx context.Context, in *struct{ Foo string }) interface{} {
+		ep.InjectTraceContext(ctx, out)
+		return in
+	})
+
+	r := httptest.NewRequest("POST", "/foo", strings.NewReader(`{"Foo":"bar"}`))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	r.Header.Set("tracestate", "foo=bar")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if names := strings.Join(rec.Names(), ","); names != "ep.negotiate,ep.bind,ep.call,ep.response_hooks,ep.encode,ep.request" {
+		t.Fatalf("unexpected spans, got: %v", names)
+	}
+
+	spans := rec.Spans()
+	root := spans[5]
+	if root.Parent.String() != "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01" || root.TraceContext.State != "foo=bar" {
+		t.Fatalf("root span should continue the client's trace, got: %v", root.Parent)
+	}
+
+	if root.Attributes["http.method"] != "POST" || root.Attributes["http.path"] != "/foo" || root.Attributes["http.status_code"] != 200 {
+		t.Fatalf("unexpected root attributes, got: %v", root.Attributes)
+	}
+
+	for _, s := range spans[:5] {
+		if s.Parent.SpanID != root.TraceContext.SpanID || s.TraceContext.TraceID != root.TraceContext.TraceID {
+			t.Fatalf("span %v should be a child of the root span", s.Name)
+		}
+	}
+
+	if out.Get("traceparent") != spans[2].TraceContext.String() || out.Get("tracestate") != "foo=bar" {
+		t.Fatalf("handler should propagate the call span, got: %v", out)
+	}
+}
+
+func TestRecordCodecErrors(t *testing.T) {
+	rec := NewRecorder()
+	ep.New(
+		ep.Tracing(rec),
+		ep.ResponseEncoding(epcoding.JSON{}),
+	).Handle(func() error {
+		return errors.New("foo")
+	}).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	spans := rec.Spans()
+	root := spans[len(spans)-1]
+	if root.Name != "ep.request" || root.Parent.IsValid() || len(root.Errors) != 1 || root.Errors[0].Error() != "foo" {
+		t.Fatalf("unexpected root span, got: %+v", root)
+	}
+}
+
+func TestRecorderReset(t *testing.T) {
+	rec := NewRecorder()
+	_, span := rec.Start(context.Background(), "foo")
+	span.End()
+	span.End()
+
+	if len(rec.Spans()) != 1 {
+		t.Fatalf("span should be recorded once, got: %v", rec.Names())
+	}
+
+	rec.Reset()
+	if len(rec.Spans()) != 0 {
+		t.Fatalf("should have reset, got: %v", rec.Names())
+	}
+}
@@ -7,22 +7,86 @@ type Op string
 type ErrorKind uint8
 
 const (
-	OtherError        ErrorKind = iota
-	ServerError                 // unexpected server condition
-	UnacceptableError           // no encoder supports what the client accepts
-	UnsupportedError            // no decoder supports the content type sent by the client
-	RequestHookError            // request hook failed to run
-	DecoderError                // decoder failed while decoding
-	EncoderError                // encoder failed while encoding
+	OtherError                ErrorKind = iota
+	ServerError                         // unexpected server condition
+	UnacceptableError                   // no encoder supports what the client accepts
+	UnsupportedError                    // no decoder supports the content type sent by the client
+	RequestHookError                    // request hook failed to run
+	DecoderError                        // decoder failed while decoding
+	EncoderError                        // encoder failed while encoding
+	PreconditionFailedError             // a request precondition evaluated to false
+	PreconditionRequiredError           // the request was required to be conditional
+	NotFoundError                       // the requested resource does not exist
+	ConflictError                       // the request conflicts with the resource state
+	UnauthorizedError                   // the client is not authenticated
+	ForbiddenError                      // the client is not allowed to do this
+	InvalidError                        // the client provided invalid input
+	RateLimitedError                    // the client sent too many requests
+	UnavailableError                    // a dependency is (temporarily) unavailable
+	TimeoutError                        // the operation took too long to complete
+	MethodNotAllowedError               // the resource doesn't support the request method
 )
 
+var kindNames = [...]string{
+	OtherError:                "other",
+	ServerError:               "server",
+	UnacceptableError:         "unacceptable",
+	UnsupportedError:          "unsupported",
+	RequestHookError:          "request hook",
+	DecoderError:              "decoder",
+	EncoderError:              "encoder",
+	PreconditionFailedError:   "precondition failed",
+	PreconditionRequiredError: "precondition required",
+	NotFoundError:             "not found",
+	ConflictError:             "conflict",
+	UnauthorizedError:         "unauthorized",
+	ForbiddenError:            "forbidden",
+	InvalidError:              "invalid",
+	RateLimitedError:          "rate limited",
+	UnavailableError:          "unavailable",
+	TimeoutError:              "timeout",
+	MethodNotAllowedError:     "method not allowed",
+}
+
+func (k ErrorKind) String() string {
+	if int(k) < len(kindNames) {
+		return kindNames[k]
+	}
+
+	return "unknown"
+}
+
+// PublicMsg can be provided to Err as a message that is safe to be shown to
+// the client, unlike the regular message which is only meant for logging.
+type PublicMsg string
+
+// ErrorCode can be provided to Err as a machine-readable code for the client
+type ErrorCode string
+
+// ErrorDetails can be provided to Err as additional information for the
+// client, for example which input fields were invalid.
+type ErrorDetails map[string]interface{}
+
 type Error struct {
-	msg  string
-	err  error
-	op   Op
-	kind ErrorKind
+	msg     string
+	err     error
+	op      Op
+	kind    ErrorKind
+	public  PublicMsg
+	code    ErrorCode
+	details ErrorDetails
+	stack   []byte
 }
 
+// Op returns the operation of just this error
+func (e *Error) Op() Op { return e.op }
+
+// Kind returns the kind of just this error
+func (e *Error) Kind() ErrorKind { return e.kind }
+
+// Message returns the (private) message of just this error
+func (e *Error) Message() string { return e.msg }
+
 func (e *Error) Unwrap() error {
 	return e.err
 }
@@ -45,6 +109,10 @@ func (e *Error) Is(target error) bool {
 		return false
 	}
 
+	if terr.code != "" && terr.code != e.code {
+		return false
+	}
+
 	if terr.err != nil {
 		return errors.Is(e.err, terr.err)
 	}
@@ -64,6 +132,9 @@ func (e *Error) Error() string {
 	return string(e.op) + ": " + e.msg + ": " + e.err.Error()
 }
 
+// Err builds an error from the provided arguments. Multiple error arguments
+// are joined together. If no kind is provided the kind is lifted from the
+// wrapped error(s), such that it is not lost when errors are nested.
 func Err(args ...interface{}) *Error {
 	e := &Error{}
 	for _, arg := range args {
@@ -71,15 +142,150 @@ func Err(args ...interface{}) *Error {
 		case Op:
 			e.op = at
 		case error:
+			if e.err != nil {
+				e.err = errors.Join(e.err, at)
+				break
+			}
+
 			e.err = at
 		case string:
 			e.msg = at
 		case ErrorKind:
 			e.kind = at
+		case PublicMsg:
+			e.public = at
+		case ErrorCode:
+			e.code = at
+		case ErrorDetails:
+			e.details = at
 		default:
 			panic("ep: unsupported argument for building error")
 		}
 	}
 
+	if e.kind == OtherError && e.err != nil {
+		e.kind = Kind(e.err)
+	}
+
 	return e
 }
+
+// Kind returns the kind of the first ep.Error in the error's tree that has a
+// kind other then OtherError. The tree is traversed depth-first and includes
+// errors that were joined together.
+func Kind(err error) (kind ErrorKind) {
+	walkErrors(err, func(e *Error) bool {
+		kind = e.kind
+		return kind == OtherError
+	})
+
+	return
+}
+
+// Public returns the first client-safe message in the error's tree or an empty
+// string if none of the errors has one.
+func Public(err error) (msg string) {
+	walkErrors(err, func(e *Error) bool {
+		msg = string(e.public)
+		return msg == ""
+	})
+
+	return
+}
+
+// Code returns the first machine-readable error code in the error's tree
+func Code(err error) (code string) {
+	walkErrors(err, func(e *Error) bool {
+		code = string(e.code)
+		return code == ""
+	})
+
+	return
+}
+
+// Details returns the first error details in the error's tree
+func Details(err error) (details ErrorDetails) {
+	walkErrors(err, func(e *Error) bool {
+		details = e.details
+		return details == nil
+	})
+
+	return
+}
+
+// Stack returns the first goroutine stack trace that was captured in the
+// error's tree, for example when a panic was recovered.
+func Stack(err error) (stack []byte) {
+	walkErrors(err, func(e *Error) bool {
+		stack = e.stack
+		return stack == nil
+	})
+
+	return
+}
+
+// Ops returns the operations of all ep.Errors in the error's tree, starting
+// with the outermost. It is useful as a logical stack trace for logging.
+func Ops(err error) (ops []Op) {
+	walkErrors(err, func(e *Error) bool {
+		if e.op != "" {
+			ops = append(ops, e.op)
+		}
+
+		return true
+	})
+
+	return
+}
+
+// walkErrors calls 'f' for each ep.Error in the tree of 'err' until it
+// returns false.
+func walkErrors(err error, f func(e *Error) bool) bool {
+	if err == nil {
+		return true
+	}
+
+	if e, ok := err.(*Error); ok && !f(e) {
+		return false
+	}
+
+	switch et := err.(type) {
+	case interface{ Unwrap() error }:
+		return walkErrors(et.Unwrap(), f)
+	case interface{ Unwrap() []error }:
+		for _, err := range et.Unwrap() {
+			if !walkErrors(err, f) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// NotFound builds an error of the NotFoundError kind, see Err for the arguments
+func NotFound(args ...interface{}) *Error { return Err(append(args, NotFoundError)...) }
+
+// Conflict builds an error of the ConflictError kind, see Err for the arguments
+func Conflict(args ...interface{}) *Error { return Err(append(args, ConflictError)...) }
+
+// Unauthorized builds an error of the UnauthorizedError kind, see Err for the
+// arguments
+func Unauthorized(args ...interface{}) *Error { return Err(append(args, UnauthorizedError)...) }
+
+// Forbidden builds an error of the ForbiddenError kind, see Err for the arguments
+func Forbidden(args ...interface{}) *Error { return Err(append(args, ForbiddenError)...) }
+
+// Invalid builds an error of the InvalidError kind, see Err for the arguments
+func Invalid(args ...interface{}) *Error { return Err(append(args, InvalidError)...) }
+
+// RateLimited builds an error of the RateLimitedError kind, see Err for the
+// arguments
+func RateLimited(args ...interface{}) *Error { return Err(append(args, RateLimitedError)...) }
+
+// Unavailable builds an error of the UnavailableError kind, see Err for the
+// arguments
+func Unavailable(args ...interface{}) *Error { return Err(append(args, UnavailableError)...) }
+
+// Timeout builds an error of the TimeoutError kind, see Err for the arguments
+func Timeout(args ...interface{}) *Error { return Err(append(args, TimeoutError)...) }
@@ -2,6 +2,8 @@ package ep
 
 import (
 	"errors"
+	"fmt"
+	"reflect"
 	"strconv"
 	"testing"
 )
@@ -84,3 +86,111 @@ func TestErrorMatching(t *testing.T) {
 	}
 
 }
+
+func TestErrorKindHelpers(t *testing.T) {
+	for i, c := range []struct {
+		err     *Error
+		expKind ErrorKind
+		expStr  string
+	}{
+		{NotFound("foo"), NotFoundError, "not found"},
+		{Conflict(Op("my.op")), ConflictError, "conflict"},
+		{Unauthorized(), UnauthorizedError, "unauthorized"},
+		{Forbidden(), ForbiddenError, "forbidden"},
+		{Invalid(errors.New("foo")), InvalidError, "invalid"},
+		{RateLimited(), RateLimitedError, "rate limited"},
+		{Unavailable(), UnavailableError, "unavailable"},
+		{Timeout(), TimeoutError, "timeout"},
+		{Err(ErrorKind(155)), ErrorKind(155), "unknown"},
+	} {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			if !errors.Is(c.err, Err(c.expKind)) {
+				t.Fatalf("expected error of kind %v, got: %#v", c.expKind, c.err)
+			}
+
+			if c.expKind.String() != c.expStr {
+				t.Fatalf("expected kind string %s, got: %s", c.expStr, c.expKind.String())
+			}
+		})
+	}
+}
+
+func TestErrorKindLifting(t *testing.T) {
+	e1 := Err(Op("inner.op"), "decode failed", DecoderError)
+	e2 := Err(Op("outer.op"), "bind failed", e1)
+	e3 := Err(Op("outermost.op"), "request failed", e2, ServerError)
+
+	if !errors.Is(e2, Err(Op("outer.op"), DecoderError)) {
+		t.Fatalf("outer error should have lifted the inner kind, got: %v", e2.kind)
+	}
+
+	if e3.kind != ServerError || Kind(e3) != ServerError {
+		t.Fatalf("explicit kind should not be overwritten, got: %v", e3.kind)
+	}
+
+	if Kind(fmt.Errorf("wrapped: %w", e2)) != DecoderError {
+		t.Fatalf("kind should be found in wrapped errors")
+	}
+
+	if Kind(errors.New("foo")) != OtherError || Kind(nil) != OtherError {
+		t.Fatalf("errors without kinds should be of the other kind")
+	}
+
+	ops := Ops(fmt.Errorf("wrapped: %w", e3))
+	if !reflect.DeepEqual(ops, []Op{"outermost.op", "outer.op", "inner.op"}) {
+		t.Fatalf("unexpected ops, got: %v", ops)
+	}
+}
+
+func TestErrorJoining(t *testing.T) {
+	e1 := errors.New("foo")
+	e2 := Err(Op("second.op"), NotFoundError)
+	e3 := Err(Op("third.op"), ConflictError)
+
+	e4 := Err(Op("join.op"), e1, e2, e3)
+	if e4.kind != NotFoundError {
+		t.Fatalf("expected first kind to be lifted, got: %v", e4.kind)
+	}
+
+	if !errors.Is(e4, e1) || !errors.Is(e4, e3) {
+		t.Fatalf("joined error should match each of its errors")
+	}
+
+	if !errors.Is(e4, Err(Op("third.op"), ConflictError)) {
+		t.Fatalf("joined error should match kinds of each of its errors")
+	}
+
+	ops := Ops(errors.Join(e4, Err(Op("other.op"))))
+	if !reflect.DeepEqual(ops, []Op{"join.op", "second.op", "third.op", "other.op"}) {
+		t.Fatalf("unexpected ops, got: %v", ops)
+	}
+
+	if Kind(errors.Join(e1, e3)) != ConflictError {
+		t.Fatalf("kind should be found in joined errors")
+	}
+}
+
+func TestErrorPublicMessage(t *testing.T) {
+	e1 := Err(Op("inner.op"), "db: duplicate key", PublicMsg("name is taken"), ErrorCode("name_taken"))
+	e2 := Err(Op("outer.op"), "failed to create", e1, ErrorDetails{"field": "name"})
+
+	if e2.Error() != "outer.op: failed to create: db: duplicate key" {
+		t.Fatalf("public message should not be part of the error string, got: %v", e2.Error())
+	}
+
+	if Public(e2) != "name is taken" || Code(e2) != "name_taken" {
+		t.Fatalf("unexpected, got: %v %v", Public(e2), Code(e2))
+	}
+
+	if !reflect.DeepEqual(Details(e2), ErrorDetails{"field": "name"}) {
+		t.Fatalf("unexpected, got: %v", Details(e2))
+	}
+
+	if !errors.Is(e2, Err(ErrorCode("name_taken"))) || errors.Is(e1, Err(ErrorCode("other"))) {
+		t.Fatalf("errors should match on code")
+	}
+
+	if Public(errors.New("foo")) != "" || Code(nil) != "" || Details(nil) != nil {
+		t.Fatalf("should be empty for non ep errors")
+	}
+}
@@ -1,5 +1,5 @@
 module github.com/advanderveer/ep
 
-go 1.14
+go 1.22
 
 
@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"net/http"
+
+	"github.com/advanderveer/ep"
 )
 
 type (
@@ -18,15 +20,17 @@ func (h *handler) CreateIdea(
 	ctx context.Context,
 	in CreateIdeaInput,
 ) (out *CreateIdeaOutput, err error) {
+	const op ep.Op = "rest.CreateIdea"
+
 	err = in.Validate()
 	if err != nil {
-		return nil, ErrorOutput{422, err.Error()}
+		return nil, ep.Invalid(op, err, ep.PublicMsg(err.Error()))
 	}
 
 	h.Lock()
 	defer h.Unlock()
 	if _, ok := h.db[in.Name]; ok {
-		return nil, ErrorOutput{409, "Idea already exists"}
+		return nil, ep.Conflict(op, "idea exists", ep.PublicMsg("Idea already exists"))
 	}
 
 	h.db[in.Name] = map[string]string{"name": in.Name}
@@ -12,40 +12,28 @@ import (
 )
 
 type handler struct {
-	db    map[string]map[string]string
-	codec *ep.Codec
+	db map[string]map[string]string
 	sync.Mutex
 }
 
-func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	switch r.URL.Path {
-	case "/idea":
-		switch r.Method {
-		case http.MethodPost:
-			h.codec.Handle(h.CreateIdea).ServeHTTP(w, r)
-		case http.MethodGet:
-			h.codec.Handle(h.ListIdeas).ServeHTTP(w, r)
-		default:
-			h.codec.Handle(h.MethodNotAllowed).ServeHTTP(w, r)
-		}
-	default:
-		h.codec.Handle(func(w ep.ResponseWriter, r *http.Request) {
-			w.Render(h.NotFound())
-		}).ServeHTTP(w, r)
-	}
-}
-
 func New() http.Handler {
 	logs := log.New(os.Stderr, "", 0)
+	h := &handler{db: map[string]map[string]string{
+		"existing": {"name": "existing"},
+	}}
 
-	return &handler{codec: ep.New(
+	rt := ep.NewRouter(ep.New(
 		ep.ErrorHook(ephook.NewStandardError(logs)),
 		ep.RequestDecoding(epcoding.JSON{}),
 		ep.ResponseEncoding(epcoding.JSON{}),
-		ep.ResponseHook(ephook.Status),
+		ep.BufferOutputs(),
+		ep.ResponseHook(ephook.Conditional),
+		ephook.StatusHook,
 		ep.ResponseHook(ephook.Head),
 		ep.RequestHook(ephook.Read),
-	), db: map[string]map[string]string{
-		"existing": {"name": "existing"},
-	}}
+	))
+
+	rt.Route(http.MethodPost, "/idea", h.CreateIdea)
+	rt.Route(http.MethodGet, "/idea", h.ListIdeas)
+	return rt
 }
@@ -30,3 +30,29 @@ func TestListIdeas(t *testing.T) {
 		})
 	}
 }
+
+func TestListIdeasNotModified(t *testing.T) {
+	h := New()
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/idea", nil)
+	h.ServeHTTP(w, r)
+
+	etag := w.Header().Get("ETag")
+	if etag == "" {
+		t.Fatalf("expected etag, got: %v", w.Header())
+	}
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest("GET", "/idea", nil)
+	r.Header.Set("If-None-Match", etag)
+	h.ServeHTTP(w, r)
+
+	if w.Code != 304 {
+		t.Fatalf("expected 304, got: %d", w.Code)
+	}
+
+	if w.Body.Len() != 0 {
+		t.Fatalf("expected empty body, got: %s", w.Body.String())
+	}
+}
@@ -18,4 +18,18 @@ func TestMethodNotAllowed(t *testing.T) {
 	if w.Body.String() != `{"message":"Method Not Allowed"}`+"\n" {
 		t.Fatalf("unexpected, got: %s", w.Body.String())
 	}
+
+	if w.Header().Get("Allow") != "GET, HEAD, OPTIONS, POST" {
+		t.Fatalf("unexpected, got: %s", w.Header().Get("Allow"))
+	}
+}
+
+func TestOptions(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("OPTIONS", "/idea", nil)
+	New().ServeHTTP(w, r)
+
+	if w.Code != 204 || w.Header().Get("Allow") != "GET, HEAD, OPTIONS, POST" {
+		t.Fatalf("unexpected, got: %d %s", w.Code, w.Header().Get("Allow"))
+	}
 }
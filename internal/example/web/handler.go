@@ -28,8 +28,8 @@ func New() http.Handler {
 	h := &handler{ep.New(
 		ep.RequestHook(ephook.Read),
 		ep.ResponseEncoding(epcoding.NewHTML(nil)),
-		ep.ResponseHook(ephook.Redirect),
-		ep.ResponseHook(ephook.Status),
+		ephook.StatusHook,
+		ephook.RedirectHook,
 		ep.ErrorHook(ephook.NewStandardError(logs)),
 	)}
 
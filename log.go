@@ -0,0 +1,103 @@
+package ep
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+)
+
+// Logger option configures the structured logger that is used for the Codec's
+// diagnostics, such as negotiation failures, encoder errors and recovered
+// panics. Without this option diagnostics are logged to slog.Default().
+//
+// With this option every request's context also carries a logger that is
+// decorated with attributes of the request, it can be retrieved by handlers
+// and hooks with LoggerFromContext.
+func Logger(logs *slog.Logger) Option {
+	return logger{logs}
+}
+
+type logger struct{ logs *slog.Logger }
+
+func (o logger) apply(c *Codec) {
+	c.logs = o.logs
+}
+
+type loggerKey struct{}
+
+// LoggerFromContext returns the request-scoped logger that the Codec injected
+// into the request context. If the Codec was not configured with the Logger
+// option it returns slog.Default().
+func LoggerFromContext(ctx context.Context) *slog.Logger {
+	if logs, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return logs
+	}
+
+	return slog.Default()
+}
+
+// withLogger returns a shallow copy of the request with a request-scoped
+// logger in its context.
+func withLogger(r *http.Request, logs *slog.Logger) (*http.Request, *slog.Logger) {
+	logs = requestLogger(logs, r)
+	return r.WithContext(context.WithValue(r.Context(), loggerKey{}, logs)), logs
+}
+
+// requestLogger decorates the logger with attributes of the request
+func requestLogger(logs *slog.Logger, r *http.Request) *slog.Logger {
+	if r == nil {
+		return logs
+	}
+
+	return logs.With(RequestAttrs(r)...)
+}
+
+// RequestAttrs returns structured logging attributes for the request: the
+// method, path and request ID (if it has one).
+func RequestAttrs(r *http.Request) []any {
+	attrs := []any{slog.String("method", r.Method), slog.String("path", r.URL.Path)}
+	if rid := RequestIDFromContext(r.Context()); rid != "" {
+		attrs = append(attrs, slog.String("request_id", rid))
+	}
+
+	return attrs
+}
+
+// ErrorAttrs returns structured logging attributes for the error: the error
+// message, the outermost op and its kind (if it has any).
+func ErrorAttrs(err error) []any {
+	attrs := []any{slog.String("error", err.Error())}
+	if ops := Ops(err); len(ops) > 0 {
+		attrs = append(attrs, slog.String("op", string(ops[0])))
+	}
+
+	if kind := Kind(err); kind != OtherError {
+		attrs = append(attrs, slog.String("kind", kind.String()))
+	}
+
+	return attrs
+}
+
+// errorLevel returns the level to log the error at. Errors that are caused by
+// the client are logged at the info level such that clients cannot flood the
+// logs with errors.
+func errorLevel(err error) slog.Level {
+	switch Kind(err) {
+	case UnacceptableError, UnsupportedError, DecoderError,
+		PreconditionFailedError, PreconditionRequiredError, NotFoundError,
+		ConflictError, UnauthorizedError, ForbiddenError, InvalidError,
+		RateLimitedError, MethodNotAllowedError:
+		return slog.LevelInfo
+	}
+
+	return slog.LevelError
+}
+
+// logger returns the logger for the response's diagnostics
+func (res *response) logger() *slog.Logger {
+	if res.logs == nil {
+		res.logs = requestLogger(slog.Default(), res.req)
+	}
+
+	return res.logs
+}
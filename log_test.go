@@ -0,0 +1,108 @@
+package ep
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/advanderveer/ep/epcoding"
+)
+
+func TestLoggerFromContext(t *testing.T) {
+	if LoggerFromContext(context.Background()) != slog.Default() {
+		t.Fatalf("should fall back to default logger")
+	}
+
+	buf := bytes.NewBuffer(nil)
+	logs := slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+
+			return a
+		},
+	}))
+
+	r := httptest.NewRequest("GET", "/foo", nil)
+	r.Header.Set("X-Request-ID", "abc")
+	w := httptest.NewRecorder()
+	New(Logger(logs), RequestID(RequestIDConfig{IgnoreIncoming: true, Generate: func() string { return "def" }})).Handle(func(ctx context.Context) {
+		LoggerFromContext(ctx).Info("hello")
+	}).ServeHTTP(w, r)
+
+	if buf.String() != "level=INFO msg=hello method=GET path=/foo request_id=def\n" {
+		t.Fatalf("unexpected log, got: %v", buf.String())
+	}
+}
+
+func TestLoggerDiagnostics(t *testing.T) {
+	for i, c := range []struct {
+		handle interface{}
+		accept string
+		expLog string
+	}{
+		{
+			handle: func() error { return Err(Op("foo.bar"), "foo", NotFoundError) },
+			expLog: "level=WARN msg=\"no error hooks to render error\" method=GET path=/ error=foo op=foo.bar kind=\"not found\"\n",
+		},
+		{
+			handle: func() interface{} { return make(chan struct{}) },
+			expLog: "level=ERROR msg=\"failed to render output\" method=GET path=/ error=\"response.render: response body encoder failed: json: unsupported type: chan struct {}\" op=response.render kind=encoder content_type=application/json\n",
+		},
+		{
+			handle: func() string { return "foo" },
+			accept: "text/xml",
+			expLog: "level=INFO msg=\"failed to render output\" method=GET path=/ error=",
+		},
+	} {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			buf := bytes.NewBuffer(nil)
+			logs := slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{
+				ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+					if a.Key == slog.TimeKey {
+						return slog.Attr{}
+					}
+
+					return a
+				},
+			}))
+
+			r := httptest.NewRequest("GET", "/", nil)
+			if c.accept != "" {
+				r.Header.Set("Accept", c.accept)
+			}
+
+			w := httptest.NewRecorder()
+			New(
+				Logger(logs),
+				ResponseEncoding(epcoding.JSON{}),
+			).Handle(c.handle).ServeHTTP(w, r)
+
+			if !strings.HasPrefix(buf.String(), c.expLog) {
+				t.Fatalf("unexpected log, got: %v", buf.String())
+			}
+		})
+	}
+}
+
+func TestLoggerPanic(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	logs := slog.New(slog.NewJSONHandler(buf, nil))
+
+	r := httptest.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	New(
+		Logger(logs),
+		ResponseEncoding(epcoding.JSON{}),
+	).Handle(func(w ResponseWriter, r *http.Request) { panic("foo") }).ServeHTTP(w, r)
+
+	if !strings.Contains(buf.String(), `"msg":"recovered panic","method":"GET","path":"/","panic":"foo","stack":"goroutine`) {
+		t.Fatalf("unexpected log, got: %v", buf.String())
+	}
+}
@@ -0,0 +1,122 @@
+package ep
+
+import "net/http"
+
+// Middleware option installs standard net/http middleware around every
+// handler of the Codec, the first middleware is the outermost. Unlike
+// middleware that wraps the handler returned by Handle, it is called after
+// the request was prepared and the encoder negotiated: the writer it receives
+// implements ResponseWriter, so it can render errors (e.g. to deny a request)
+// the same way handlers do, and panics are recovered. The request it passes
+// on is used for the rest of handling, so it can add values to the context.
+// If it passes on a writer that wraps the one it received (e.g. to capture the
+// status or compress the body) the response is written through that writer,
+// after the response hooks ran and the output was encoded.
+func Middleware(mws ...func(http.Handler) http.Handler) Option {
+	return middleware(mws)
+}
+
+type middleware []func(http.Handler) http.Handler
+
+func (o middleware) apply(c *Codec) {
+	c.middleware = append(c.middleware, o...)
+}
+
+// Call represents calling a handler with the bound input, or nil if the
+// handler has no input. Handlers with multiple inputs receive them as an
+// []interface{}. It returns the outputs that are rendered afterwards, handlers
+// that take a ResponseWriter might write the response themselves and return
+// no outputs.
+type Call func(w ResponseWriter, r *http.Request, in interface{}) (outs []interface{})
+
+// CallMiddleware option wraps the call of every handler of the Codec. It is
+// called after request hooks ran and the input was bound successfully, so it
+// can inspect the input and replace the outputs before they are rendered.
+// Returning an error output without calling next denies the request. The
+// first middleware that is configured is the outermost.
+type CallMiddleware func(next Call) Call
+
+func (o CallMiddleware) apply(c *Codec) {
+	c.callMiddleware = append(c.callMiddleware, o)
+}
+
+// ClearMiddleware option removes all Middleware and CallMiddleware that was
+// configured before it.
+func ClearMiddleware() Option {
+	return clearOption(func(c *Codec) { c.middleware, c.callMiddleware = nil, nil })
+}
+
+// wrap the handling 'h' with the Codec's middleware. The middleware is
+// provided with a writer that implements ResponseWriter instead of the
+// original writer.
+func (c *Codec) wrap(res *response, h func(res *response)) {
+	if len(c.middleware) == 0 {
+		h(res)
+		return
+	}
+
+	mw := &middlewareWriter{response: res, w: res.ResponseWriter}
+	var next http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		res.req = r
+		if w != http.ResponseWriter(mw) {
+			// the middleware wrapped the writer, so the response writes to the
+			// wrapper which will eventually write to the original writer.
+			res.ResponseWriter, mw.forwarding = w, true
+		}
+
+		h(res)
+	})
+
+	for i := len(c.middleware) - 1; i >= 0; i-- {
+		next = c.middleware[i](next)
+	}
+
+	next.ServeHTTP(mw, res.req)
+}
+
+// middlewareWriter is the writer that middleware receives. Writes by the
+// middleware itself go through the response such that hooks are called, but
+// once the response writes to a wrapping writer the writes it forwards go to
+// the original writer.
+type middlewareWriter struct {
+	*response
+	w          http.ResponseWriter
+	forwarding bool
+}
+
+func (mw *middlewareWriter) Header() http.Header { return mw.w.Header() }
+
+func (mw *middlewareWriter) Write(b []byte) (int, error) {
+	if mw.forwarding {
+		return mw.w.Write(b)
+	}
+
+	return mw.response.Write(b)
+}
+
+func (mw *middlewareWriter) WriteHeader(statusCode int) {
+	if mw.forwarding {
+		mw.w.WriteHeader(statusCode)
+		return
+	}
+
+	mw.response.WriteHeader(statusCode)
+}
+
+// call the handler through the Codec's call middleware, 'f' is the final call
+func (c *Codec) call(res *response, in interface{}, f func(in interface{}) []interface{}) []interface{} {
+	if len(c.callMiddleware) == 0 {
+		return f(in)
+	}
+
+	var call Call = func(w ResponseWriter, r *http.Request, in interface{}) []interface{} {
+		res.req = r
+		return f(in)
+	}
+
+	for i := len(c.callMiddleware) - 1; i >= 0; i-- {
+		call = c.callMiddleware[i](call)
+	}
+
+	return call(res, res.req, in)
+}
@@ -0,0 +1,301 @@
+package ep
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/advanderveer/ep/epcoding"
+)
+
+type userKey struct{}
+
+type testErrorOutput struct {
+	Message string `json:"message"`
+	code    int
+}
+
+// testErrorHooks render server errors with 500 and any other error with 400
+var testErrorHooks = Options(
+	ErrorHook(func(err error) interface{} {
+		if Kind(err) == ServerError {
+			return testErrorOutput{http.StatusText(500), 500}
+		}
+
+		return testErrorOutput{http.StatusText(400), 400}
+	}),
+	PhasedResponseHook(StatusPhase, func(w http.ResponseWriter, r *http.Request, out interface{}) {
+		if o, ok := out.(testErrorOutput); ok {
+			w.WriteHeader(o.code)
+		}
+	}),
+)
+
+func TestMiddleware(t *testing.T) {
+	var calls []string
+	trace := func(name string) func(http.Handler) http.Handler {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls = append(calls, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	auth := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user := r.Header.Get("X-User")
+			if user == "" {
+				w.(ResponseWriter).Render(Err("auth", "no user", UnauthorizedError))
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userKey{}, user)))
+		})
+	}
+
+	for i, c := range []struct {
+		user     string
+		handler  interface{}
+		expCalls string
+		expCode  int
+		expBody  string
+	}{
+		{"", func(ctx context.Context) string { return "hello" }, "a,b", 400, `{"message":"Bad Request"}` + "\n"},
+		{"foo", func(ctx context.Context) string { return "hello " + ctx.Value(userKey{}).(string) }, "a,b", 200, `"hello foo"` + "\n"},
+		{"foo", func(w ResponseWriter, r *http.Request) {
+			w.Render("hi " + r.Context().Value(userKey{}).(string))
+		}, "a,b", 200, `"hi foo"` + "\n"},
+		{"foo", func() { panic("boom") }, "a,b", 500, `{"message":"Internal Server Error"}` + "\n"},
+	} {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			calls = nil
+			h := New(
+				ResponseEncoding(epcoding.JSON{}),
+				testErrorHooks,
+				Middleware(trace("a"), trace("b")),
+				Middleware(auth),
+			).Handle(c.handler)
+
+			r := httptest.NewRequest("GET", "/", nil)
+			r.Header.Set("X-User", c.user)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if strings.Join(calls, ",") != c.expCalls {
+				t.Fatalf("expected calls %v, got: %v", c.expCalls, calls)
+			}
+
+			if w.Code != c.expCode {
+				t.Fatalf("expected code %v, got: %v", c.expCode, w.Code)
+			}
+
+			if w.Body.String() != c.expBody {
+				t.Fatalf("expected body %v, got: %v", c.expBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestCallMiddleware(t *testing.T) {
+	type input struct{ Name string }
+
+	var calls []string
+	trace := func(name string) CallMiddleware {
+		return func(next Call) Call {
+			return func(w ResponseWriter, r *http.Request, in interface{}) []interface{} {
+				calls = append(calls, name+":"+strconv.Quote(in.(*input).Name))
+				outs := next(w, r, in)
+				calls = append(calls, name+":"+strconv.Itoa(len(outs)))
+				return outs
+			}
+		}
+	}
+
+	deny := CallMiddleware(func(next Call) Call {
+		return func(w ResponseWriter, r *http.Request, in interface{}) []interface{} {
+			if in.(*input).Name == "bar" {
+				return []interface{}{Err("deny", "not allowed", ForbiddenError)}
+			}
+
+			return next(w, r, in)
+		}
+	})
+
+	upper := CallMiddleware(func(next Call) Call {
+		return func(w ResponseWriter, r *http.Request, in interface{}) []interface{} {
+			outs := next(w, r, in)
+			for i, out := range outs {
+				if s, ok := out.(string); ok {
+					outs[i] = strings.ToUpper(s)
+				}
+			}
+
+			return outs
+		}
+	})
+
+	for i, c := range []struct {
+		body     string
+		expCalls string
+		expCode  int
+		expBody  string
+	}{
+		{`{"Name":"foo"}`, `a:"foo",b:"foo",b:2,a:2`, 200, `"HELLO FOO"` + "\n"},
+		{`{"Name":"bar"}`, `a:"bar",a:1`, 400, `{"message":"Bad Request"}` + "\n"},
+		{`{"Name":`, ``, 400, `{"message":"Bad Request"}` + "\n"},
+	} {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			calls = nil
+			h := New(
+				RequestDecoding(epcoding.JSON{}),
+				ResponseEncoding(epcoding.JSON{}),
+				testErrorHooks,
+				trace("a"), deny, trace("b"), upper,
+			).Handle(func(in input) (string, error) { return "hello " + in.Name, nil })
+
+			r := httptest.NewRequest("POST", "/", strings.NewReader(c.body))
+			r.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if strings.Join(calls, ",") != c.expCalls {
+				t.Fatalf("expected calls %v, got: %v", c.expCalls, calls)
+			}
+
+			if w.Code != c.expCode {
+				t.Fatalf("expected code %v, got: %v", c.expCode, w.Code)
+			}
+
+			if w.Body.String() != c.expBody {
+				t.Fatalf("expected body %v, got: %v", c.expBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestCallMiddlewareResponseWriterHandler(t *testing.T) {
+	deny := CallMiddleware(func(next Call) Call {
+		return func(w ResponseWriter, r *http.Request, in interface{}) []interface{} {
+			if r.URL.Query().Get("deny") != "" {
+				return []interface{}{Err("deny", "not allowed", ForbiddenError)}
+			}
+
+			return next(w, r, in)
+		}
+	})
+
+	c := New(ResponseEncoding(epcoding.JSON{}), testErrorHooks, deny)
+	h := c.Handle(func(w ResponseWriter, r *http.Request) { w.Write([]byte("ok")) })
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != 200 || w.Body.String() != "ok" {
+		t.Fatalf("unexpected response, got: %v %v", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/?deny=1", nil))
+	if w.Code != 400 {
+		t.Fatalf("unexpected response, got: %v %v", w.Code, w.Body.String())
+	}
+
+	// middleware can be cleared per handler
+	w = httptest.NewRecorder()
+	c.Handle(func(w ResponseWriter, r *http.Request) { w.Write([]byte("ok")) }, ClearMiddleware()).
+		ServeHTTP(w, httptest.NewRequest("GET", "/?deny=1", nil))
+	if w.Code != 200 {
+		t.Fatalf("unexpected response, got: %v %v", w.Code, w.Body.String())
+	}
+}
+
+type captureWriter struct {
+	http.ResponseWriter
+	code  int
+	bytes int
+}
+
+func (w *captureWriter) WriteHeader(code int) {
+	w.code = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *captureWriter) Write(b []byte) (int, error) {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+
+	w.bytes += len(b)
+	return w.ResponseWriter.Write(b)
+}
+
+type upperWriter struct{ http.ResponseWriter }
+
+func (w upperWriter) Write(b []byte) (int, error) {
+	return w.ResponseWriter.Write(bytes.ToUpper(b))
+}
+
+func TestMiddlewareWrappedWriter(t *testing.T) {
+	var captured *captureWriter
+	capture := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			captured = &captureWriter{ResponseWriter: w}
+			next.ServeHTTP(captured, r)
+		})
+	}
+
+	upper := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(upperWriter{w}, r)
+		})
+	}
+
+	deny := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("deny") != "" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	for i, c := range []struct {
+		handler  interface{}
+		target   string
+		expCode  int
+		expBody  string
+		expBytes int
+	}{
+		{func() string { return "hello" }, "/", 200, `"HELLO"` + "\n", 8},
+		{func(w ResponseWriter, r *http.Request) { w.WriteHeader(201); w.Write([]byte("hi")) }, "/", 201, "HI", 2},
+		{func() string { return "hello" }, "/?deny=1", 403, "", 0},
+	} {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			w := httptest.NewRecorder()
+			New(
+				ResponseEncoding(epcoding.JSON{}),
+				RequestID(RequestIDConfig{}),
+				Middleware(capture, upper, deny),
+			).Handle(c.handler).ServeHTTP(w, httptest.NewRequest("GET", c.target, nil))
+
+			if w.Code != c.expCode || captured.code != c.expCode {
+				t.Fatalf("expected code %v, got: %v (captured: %v)", c.expCode, w.Code, captured.code)
+			}
+
+			if w.Body.String() != c.expBody || captured.bytes != c.expBytes {
+				t.Fatalf("expected body %q, got: %q (captured %d bytes)", c.expBody, w.Body.String(), captured.bytes)
+			}
+
+			// response hooks still run
+			if w.Header().Get("X-Request-ID") == "" {
+				t.Fatalf("expected request id, got: %v", w.Header())
+			}
+		})
+	}
+}
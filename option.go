@@ -2,6 +2,7 @@ package ep
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/advanderveer/ep/epcoding"
 )
@@ -53,10 +54,14 @@ func (o requestDecoding) apply(c *Codec) {
 // before is send for the response. It is provided with the output that is
 // currently rendered but if the response is called without using the render
 // method this argument might be nil.
+//
+// The hook is added to the HeadersPhase, use PhasedResponseHook to add it to
+// another phase. The first hook that writes the header wins, so hooks that
+// write the header should be added to a later phase.
 type ResponseHook func(w http.ResponseWriter, r *http.Request, out interface{})
 
 func (o ResponseHook) apply(c *Codec) {
-	c.resHooks = append(c.resHooks, o)
+	c.addResponseHook(HeadersPhase, o)
 }
 
 // RequestHook option allows reading arbitrary properties on the request to
@@ -70,6 +75,16 @@ func (o RequestHook) apply(c *Codec) {
 	c.reqHooks = append(c.reqHooks, o)
 }
 
+// DecodedHook option is a variant of the RequestHook that is called after the
+// request body was decoded into the input. It can be used to set values that
+// the client must not be able to override with the body, such as the
+// parameters in the request path.
+type DecodedHook func(r *http.Request, in interface{}) error
+
+func (o DecodedHook) apply(c *Codec) {
+	c.decodedHooks = append(c.decodedHooks, RequestHook(o))
+}
+
 // ErrorHook can be provided as an option to be called whenever an error is
 // about to be rendered. The error can be logged or an output type can be
 // returend to customize how the error will be turned into a response.
@@ -82,5 +97,104 @@ func (o RequestHook) apply(c *Codec) {
 type ErrorHook func(err error) (out interface{})
 
 func (o ErrorHook) apply(c *Codec) {
+	c.errHooks = append(c.errHooks, o.withRequest())
+}
+
+// withRequest turns the error hook into its request-aware variant
+func (o ErrorHook) withRequest() RequestErrorHook {
+	return func(_ *http.Request, _ string, err error) interface{} { return o(err) }
+}
+
+// RequestErrorHook is a variant of the ErrorHook that is also provided with
+// the request and the negotiated content type of the response. The content
+// type is empty if no encoder could be negotiated. It can be configured
+// alongside regular error hooks and is called in the same order as it was
+// configured.
+type RequestErrorHook func(r *http.Request, contentType string, err error) (out interface{})
+
+func (o RequestErrorHook) apply(c *Codec) {
 	c.errHooks = append(c.errHooks, o)
 }
+
+// BufferOutputs option will cause each rendered output to be encoded into
+// memory before it is written to the response. This allows response hooks to
+// inspect the encoded body using EncodedBody, at the cost of holding each
+// encoded output in memory.
+func BufferOutputs() Option {
+	return bufferOutputs{}
+}
+
+type bufferOutputs struct{}
+
+func (o bufferOutputs) apply(c *Codec) {
+	c.bufferOutputs = true
+}
+
+// PanicHook option is called whenever a panic is recovered while handling a
+// request, before the panic is rendered as a server error. It is provided with
+// the recovered value and the stack of the panicking goroutine such that it
+// can be reported. Panics with http.ErrAbortHandler are not recovered and
+// will not be passed to this hook.
+type PanicHook func(r *http.Request, v interface{}, stack []byte)
+
+func (o PanicHook) apply(c *Codec) {
+	c.panicHooks = append(c.panicHooks, o)
+}
+
+// AfterHook option is called once for every request handled by the Codec,
+// after the handler returned and any panic was recovered. It is provided with
+// the outcome of the request which makes it suitable for access logs and
+// metrics. The response can no longer be modified at this point.
+type AfterHook func(r *http.Request, o Outcome)
+
+func (o AfterHook) apply(c *Codec) {
+	c.afterHooks = append(c.afterHooks, o)
+}
+
+// Outcome describes how a request was responded to
+type Outcome struct {
+	Status      int           // status code that was send, 200 if nothing was written
+	Written     int64         // number of body bytes written
+	Duration    time.Duration // time since the request was received by the Codec
+	Output      interface{}   // last output that was rendered, if any
+	ContentType string        // content type of the negotiated encoder, if any
+	Err         error         // last error that was rendered, including encoding failures
+}
+
+// ClearResponseEncodings option removes all response encodings that were
+// configured before it, for example those inherited from a parent Codec.
+func ClearResponseEncodings() Option { return clearOption(func(c *Codec) { c.encodings = nil }) }
+
+// ClearRequestDecodings option removes all request decodings that were
+// configured before it.
+func ClearRequestDecodings() Option { return clearOption(func(c *Codec) { c.decodings = nil }) }
+
+// ClearRequestHooks option removes all request hooks, including decoded hooks,
+// that were configured before it.
+func ClearRequestHooks() Option {
+	return clearOption(func(c *Codec) { c.reqHooks, c.decodedHooks = nil, nil })
+}
+
+// ClearResponseHooks option removes all response hooks that were configured
+// before it. The hooks of the CORS and RequestID options are kept since those
+// options would be broken without them.
+func ClearResponseHooks() Option {
+	return clearOption(func(c *Codec) {
+		c.resHooks, c.resPhases = nil, nil
+		if c.cors != nil {
+			c.addResponseHook(firstPhase, c.cors.hook)
+		}
+
+		if c.requestID != nil {
+			c.addResponseHook(firstPhase, c.requestID.hook)
+		}
+	})
+}
+
+// ClearErrorHooks option removes all (request) error hooks that were
+// configured before it.
+func ClearErrorHooks() Option { return clearOption(func(c *Codec) { c.errHooks = nil }) }
+
+type clearOption func(c *Codec)
+
+func (o clearOption) apply(c *Codec) { o(c) }
@@ -0,0 +1,31 @@
+package ep
+
+import (
+	"errors"
+
+	"github.com/advanderveer/ep/epcoding"
+)
+
+// Patch is implemented by the patch documents of the epcoding package, such as
+// epcoding.MergePatch and epcoding.Patch.
+type Patch interface {
+	Apply(v interface{}) error
+}
+
+// ApplyPatch applies the patch 'p' that was decoded from the request onto the
+// value 'v' that the handler provides. If the patch cannot be applied, e.g.
+// because a test operation failed, a DecoderError is returned such that it is
+// rendered the same as a patch that failed to decode.
+func ApplyPatch(p Patch, v interface{}) error {
+	const op Op = "ep.ApplyPatch"
+
+	if err := p.Apply(v); err != nil {
+		if errors.Is(err, epcoding.InvalidPatch) {
+			return Err(op, "failed to apply patch", err, DecoderError)
+		}
+
+		return Err(op, "failed to apply patch", err, ServerError)
+	}
+
+	return nil
+}
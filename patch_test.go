@@ -0,0 +1,74 @@
+package ep
+
+import (
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/advanderveer/ep/epcoding"
+)
+
+func TestApplyPatch(t *testing.T) {
+	for i, c := range []struct {
+		patch   Patch
+		target  interface{}
+		expKind ErrorKind
+	}{
+		{patch: epcoding.MergePatch(`{"Name":"bar"}`), target: &struct{ Name string }{}},
+		{
+			patch:   epcoding.Patch{{Op: "test", Path: "/Name", Value: []byte(`"bar"`)}},
+			target:  &struct{ Name string }{"foo"},
+			expKind: DecoderError,
+		},
+		{
+			patch:   epcoding.MergePatch(`{"Name":"bar"}`),
+			target:  &struct{ C chan int }{},
+			expKind: ServerError,
+		},
+	} {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			err := ApplyPatch(c.patch, c.target)
+			if (err == nil) != (c.expKind == OtherError) || Kind(err) != c.expKind {
+				t.Fatalf("expected kind %v, got: %v", c.expKind, err)
+			}
+		})
+	}
+}
+
+func TestApplyPatchInHandler(t *testing.T) {
+	type input struct{ epcoding.Patch }
+
+	h := New(
+		testErrorHooks,
+		RequestDecoding(epcoding.JSONPatch{}),
+		ResponseEncoding(epcoding.JSON{}),
+	).Handle(func(in *input) (interface{}, error) {
+		v := &struct{ Name string }{"foo"}
+		if err := ApplyPatch(in, v); err != nil {
+			return nil, err
+		}
+
+		return v, nil
+	})
+
+	for i, c := range []struct {
+		body    string
+		expCode int
+		expBody string
+	}{
+		{`[{"op":"replace","path":"/Name","value":"bar"}]`, 200, `{"Name":"bar"}` + "\n"},
+		{`[{"op":"test","path":"/Name","value":"bar"}]`, 400, `{"message":"Bad Request"}` + "\n"},
+		{`[{"op":"remove","path":"/Age"}]`, 400, `{"message":"Bad Request"}` + "\n"},
+	} {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			r := httptest.NewRequest("PATCH", "/", strings.NewReader(c.body))
+			r.Header.Set("Content-Type", "application/json-patch+json")
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			if w.Code != c.expCode || w.Body.String() != c.expBody {
+				t.Fatalf("unexpected response, got: %v %v", w.Code, w.Body.String())
+			}
+		})
+	}
+}
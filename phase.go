@@ -0,0 +1,115 @@
+package ep
+
+import (
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+)
+
+// HookPhase determines when a response hook is called relative to other
+// response hooks. Hooks are called in the order of their phase and hooks in
+// the same phase are called in the order they were configured. Since the
+// first hook that writes the header wins, phases make the order of hooks
+// deterministic regardless of the order of the options. Any value can be used
+// as a priority, for example StatusPhase-1 to run just before the status hooks.
+type HookPhase int
+
+const (
+	HeadersPhase  HookPhase = 100 // hooks that only set headers, the default
+	RedirectPhase HookPhase = 200 // hooks that redirect, e.g. ephook.Redirect
+	StatusPhase   HookPhase = 300 // hooks that write the status, e.g. ephook.Status
+	FinalizePhase HookPhase = 400 // hooks that write the header if nothing else did
+
+	// firstPhase is used for hooks of options that add headers which must be
+	// present no matter what other hooks do (e.g. CORS). Since the header can
+	// only be modified until a hook writes it, they run before any other hook.
+	firstPhase HookPhase = math.MinInt
+)
+
+// PhasedResponseHook option adds a response hook that is called in the
+// provided phase.
+func PhasedResponseHook(phase HookPhase, h ResponseHook) Option {
+	return phasedResponseHook{phase, h}
+}
+
+type phasedResponseHook struct {
+	phase HookPhase
+	h     ResponseHook
+}
+
+func (o phasedResponseHook) apply(c *Codec) {
+	c.addResponseHook(o.phase, o.h)
+}
+
+// addResponseHook inserts the hook after all hooks of the same or an earlier
+// phase such that the hooks are always ordered by phase.
+func (c *Codec) addResponseHook(phase HookPhase, h ResponseHook) {
+	i := len(c.resPhases)
+	for i > 0 && c.resPhases[i-1] > phase {
+		i--
+	}
+
+	c.resHooks = slices.Insert(slices.Clip(c.resHooks), i, h)
+	c.resPhases = slices.Insert(slices.Clip(c.resPhases), i, phase)
+}
+
+// HookConflictMode determines what happens when a response hook conflicts
+// with another hook, see the HookConflicts option.
+type HookConflictMode int
+
+const (
+	IgnoreHookConflicts  HookConflictMode = iota // the default
+	LogHookConflicts                             // log a warning
+	PanicOnHookConflicts                         // panic, such that it fails loudly
+)
+
+// HookConflicts option enables the detection of response hooks that modify
+// the header, or try to write the status, after another hook already wrote
+// the header. Such modifications are silently discarded otherwise. Detection
+// comes with overhead so it is meant for development and tests.
+func HookConflicts(mode HookConflictMode) Option {
+	return hookConflicts{mode}
+}
+
+type hookConflicts struct{ mode HookConflictMode }
+
+func (o hookConflicts) apply(c *Codec) {
+	c.hookConflicts = o.mode
+}
+
+// hookConflict reports that hook 'h' conflicts with another hook
+func (res *response) hookConflict(h interface{}, msg string) {
+	switch res.hookConflicts {
+	case LogHookConflicts:
+		res.logger().Warn("response hook conflict", "hook", funcName(h), "conflict", msg)
+	case PanicOnHookConflicts:
+		panic("ep: response hook " + funcName(h) + " " + msg)
+	}
+}
+
+// discardedStatus reports a hook that tried to write the status after the
+// header was already written.
+func (res *response) discardedStatus(statusCode int) {
+	if res.hookConflicts == IgnoreHookConflicts || res.currentHook == nil {
+		return
+	}
+
+	res.hookConflict(res.currentHook, "tried to write status "+strconv.Itoa(statusCode)+
+		" after the header was written with "+strconv.Itoa(res.status))
+}
+
+// headerEqual compares two headers
+func headerEqual(a, b http.Header) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for k, av := range a {
+		if bv, ok := b[k]; !ok || !slices.Equal(av, bv) {
+			return false
+		}
+	}
+
+	return true
+}
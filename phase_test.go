@@ -0,0 +1,154 @@
+package ep
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/advanderveer/ep/epcoding"
+)
+
+func TestResponseHookPhases(t *testing.T) {
+	var calls []string
+	hook := func(name string) ResponseHook {
+		return func(w http.ResponseWriter, r *http.Request, out interface{}) { calls = append(calls, name) }
+	}
+
+	for i, c := range []struct {
+		opts     []Option
+		expCalls string
+	}{
+		{[]Option{ResponseHook(hook("a")), ResponseHook(hook("b"))}, "a,b"},
+		{[]Option{
+			PhasedResponseHook(FinalizePhase, hook("final")),
+			PhasedResponseHook(StatusPhase, hook("status")),
+			ResponseHook(hook("a")),
+			PhasedResponseHook(RedirectPhase, hook("redirect")),
+			ResponseHook(hook("b")),
+		}, "a,b,redirect,status,final"},
+		{[]Option{
+			PhasedResponseHook(StatusPhase, hook("status")),
+			PhasedResponseHook(StatusPhase-1, hook("before-status")),
+			PhasedResponseHook(StatusPhase, hook("status2")),
+		}, "before-status,status,status2"},
+	} {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			calls = nil
+			opts := append([]Option{ResponseEncoding(epcoding.JSON{})}, c.opts...)
+			w := httptest.NewRecorder()
+			New(opts...).Handle(func() string { return "foo" }).ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+			if strings.Join(calls, ",") != c.expCalls {
+				t.Fatalf("expected calls %v, got: %v", c.expCalls, calls)
+			}
+		})
+	}
+}
+
+type statusOutput struct{ code int }
+
+func (o statusOutput) Status() int { return o.code }
+
+func TestResponseHookPhaseOrderWins(t *testing.T) {
+	status := func(w http.ResponseWriter, r *http.Request, out interface{}) {
+		if o, ok := out.(statusOutput); ok {
+			w.WriteHeader(o.code)
+		}
+	}
+
+	setHeader := func(w http.ResponseWriter, r *http.Request, out interface{}) {
+		w.Header().Set("X-Foo", "bar")
+	}
+
+	// the status hook is configured first but the header hook runs first
+	w := httptest.NewRecorder()
+	New(
+		ResponseEncoding(epcoding.JSON{}),
+		PhasedResponseHook(StatusPhase, status),
+		ResponseHook(setHeader),
+	).Handle(func() statusOutput { return statusOutput{201} }).ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+	if w.Code != 201 {
+		t.Fatalf("expected status 201, got: %v", w.Code)
+	}
+
+	if w.Header().Get("X-Foo") != "bar" {
+		t.Fatalf("header should not be discarded, got: %v", w.Header())
+	}
+
+	// the request id is always echoed, even if configured after the status hook
+	w = httptest.NewRecorder()
+	New(
+		ResponseEncoding(epcoding.JSON{}),
+		PhasedResponseHook(HeadersPhase-1, status),
+		RequestID(RequestIDConfig{}),
+	).Handle(func() statusOutput { return statusOutput{201} }).ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+	if w.Code != 201 || w.Header().Get("X-Request-ID") == "" {
+		t.Fatalf("expected status 201 and request id, got: %v %v", w.Code, w.Header())
+	}
+}
+
+func TestHookConflicts(t *testing.T) {
+	status := func(w http.ResponseWriter, r *http.Request, out interface{}) {
+		w.WriteHeader(http.StatusCreated)
+	}
+
+	setHeader := func(w http.ResponseWriter, r *http.Request, out interface{}) {
+		w.Header().Set("X-Foo", "bar")
+	}
+
+	teapot := func(w http.ResponseWriter, r *http.Request, out interface{}) {
+		w.WriteHeader(http.StatusTeapot)
+	}
+
+	for i, c := range []struct {
+		mode     HookConflictMode
+		hooks    []Option
+		expLog   string
+		expPanic string
+	}{
+		{IgnoreHookConflicts, []Option{ResponseHook(status), ResponseHook(setHeader)}, "", ""},
+		{LogHookConflicts, []Option{ResponseHook(setHeader), ResponseHook(status)}, "", ""},
+		{LogHookConflicts, []Option{ResponseHook(status), ResponseHook(setHeader)},
+			"modified the header after it was written", ""},
+		{LogHookConflicts, []Option{ResponseHook(status), ResponseHook(teapot)},
+			"tried to write status 418 after the header was written with 201", ""},
+		{PanicOnHookConflicts, []Option{ResponseHook(status), ResponseHook(setHeader)},
+			"", "modified the header after it was written"},
+	} {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			buf := bytes.NewBuffer(nil)
+			var panicv interface{}
+			opts := append([]Option{
+				ResponseEncoding(epcoding.JSON{}),
+				HookConflicts(c.mode),
+				Logger(slog.New(slog.NewTextHandler(buf, nil))),
+				PanicHook(func(r *http.Request, v interface{}, stack []byte) { panicv = v }),
+			}, c.hooks...)
+
+			w := httptest.NewRecorder()
+			New(opts...).Handle(func() string { return "foo" }).ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+			if c.expLog == "" && strings.Contains(buf.String(), "response hook conflict") {
+				t.Fatalf("should not log conflict, got: %v", buf.String())
+			}
+
+			if c.expLog != "" && !strings.Contains(buf.String(), c.expLog) {
+				t.Fatalf("expected log to contain %v, got: %v", c.expLog, buf.String())
+			}
+
+			if c.expPanic == "" && panicv != nil {
+				t.Fatalf("should not panic, got: %v", panicv)
+			}
+
+			if c.expPanic != "" && !strings.Contains(panicv.(string), c.expPanic) {
+				t.Fatalf("expected panic to contain %v, got: %v", c.expPanic, panicv)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package ep
+
+import (
+	"net/http"
+	"reflect"
+)
+
+// Provide option registers a provider for arguments of the Codec's handler
+// functions. Providers have the signature func(*http.Request) (T, error) or
+// func(*http.Request) (T, func(), error) and handler functions can then
+// declare parameters of type T next to their context and input, for example a
+// *sql.Tx, the authenticated principal or a *slog.Logger. Providers are called
+// per request, in the order of the parameters, just before the handler is
+// called. If a provider fails the error is rendered instead of calling the
+// handler, errors without a kind are considered server errors. Cleanup
+// functions are called after the response was written, in reverse order.
+//
+// Providers are matched by their exact type and later providers for the same
+// type take precedence. Handle panics if a handler has a parameter that is
+// neither a context, an input or provided, and Provide panics if the provider
+// doesn't have one of the supported signatures.
+func Provide(f interface{}) Option {
+	p, err := newProvider(f)
+	if err != nil {
+		panic("ep: failed to turn argument into a provider: " + err.Error())
+	}
+
+	return p
+}
+
+// provider creates handler arguments of a certain type
+type provider struct {
+	typ     reflect.Type
+	fnv     reflect.Value
+	cleanup bool
+}
+
+var (
+	reqTyp     = reflect.TypeOf((*http.Request)(nil))
+	errTyp     = reflect.TypeOf((*error)(nil)).Elem()
+	cleanupTyp = reflect.TypeOf(func() {})
+)
+
+// newProvider reflects on the provider function 'f'
+func newProvider(f interface{}) (p *provider, err error) {
+	var op Op = "newProvider"
+
+	fnt := reflect.TypeOf(f)
+	if fnt == nil || fnt.Kind() != reflect.Func {
+		return nil, Err(op, "argument is not a function")
+	}
+
+	if fnt.NumIn() != 1 || fnt.In(0) != reqTyp {
+		return nil, Err(op, "function must only take a *http.Request argument")
+	}
+
+	p = &provider{fnv: reflect.ValueOf(f)}
+	switch fnt.NumOut() {
+	case 2:
+	case 3:
+		if fnt.Out(1) != cleanupTyp {
+			return nil, Err(op, "function's second result must be a func()")
+		}
+
+		p.cleanup = true
+	default:
+		return nil, Err(op, "function must return (T, error) or (T, func(), error)")
+	}
+
+	if fnt.Out(fnt.NumOut()-1) != errTyp {
+		return nil, Err(op, "function's last result must be an error")
+	}
+
+	p.typ = fnt.Out(0)
+	if canBeAssignedContext(p.typ) {
+		return nil, Err(op, "function cannot provide a context")
+	}
+
+	return p, nil
+}
+
+func (p *provider) apply(c *Codec) {
+	c.providers = append(c.providers, p)
+}
+
+// provide calls the provider, the cleanup is nil if it returned none
+func (p *provider) provide(r *http.Request) (v reflect.Value, cleanup func(), err error) {
+	outs := p.fnv.Call([]reflect.Value{reflect.ValueOf(r)})
+	if err, _ = outs[len(outs)-1].Interface().(error); err != nil {
+		return v, nil, err
+	}
+
+	if p.cleanup {
+		cleanup, _ = outs[1].Interface().(func())
+	}
+
+	return outs[0], cleanup, nil
+}
+
+// findProvider returns the last provider for type 't', or nil
+func findProvider(provs []*provider, t reflect.Type) *provider {
+	for i := len(provs) - 1; i >= 0; i-- {
+		if provs[i].typ == t {
+			return provs[i]
+		}
+	}
+
+	return nil
+}
+
+// cleanup calls the cleanup functions of providers in reverse order. Panics
+// are logged such that every cleanup gets called.
+func (res *response) cleanup() {
+	for i := len(res.cleanups) - 1; i >= 0; i-- {
+		func() {
+			defer func() {
+				if v := recover(); v != nil {
+					res.logger().Error("provider cleanup panicked", "panic", v)
+				}
+			}()
+
+			res.cleanups[i]()
+		}()
+	}
+}
@@ -0,0 +1,145 @@
+package ep
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/advanderveer/ep/epcoding"
+)
+
+func TestNewProvider(t *testing.T) {
+	for i, c := range []struct {
+		fn     interface{}
+		expErr error
+	}{
+		{nil, Err(Op("newProvider"), "argument is not a function")},
+		{func() (string, error) { return "", nil }, Err(Op("newProvider"), "function must only take a *http.Request argument")},
+		{func(*http.Request) string { return "" }, Err(Op("newProvider"), "function must return (T, error) or (T, func(), error)")},
+		{func(*http.Request) (string, string) { return "", "" }, Err(Op("newProvider"), "function's last result must be an error")},
+		{func(*http.Request) (string, func(string), error) { return "", nil, nil }, Err(Op("newProvider"), "function's second result must be a func()")},
+		{func(*http.Request) (context.Context, error) { return nil, nil }, Err(Op("newProvider"), "function cannot provide a context")},
+		{func(*http.Request) (string, error) { return "", nil }, nil},
+		{func(*http.Request) (string, func(), error) { return "", nil, nil }, nil},
+	} {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			_, err := newProvider(c.fn)
+			if !errors.Is(err, c.expErr) {
+				t.Fatalf("expected '%v', got: '%v'", c.expErr, err)
+			}
+		})
+	}
+}
+
+type principal struct{ Name string }
+
+type testTx struct{ id int }
+
+func TestProvide(t *testing.T) {
+	var calls []string
+	provs := Options(
+		Provide(func(r *http.Request) (*principal, error) {
+			calls = append(calls, "principal")
+			switch user := r.Header.Get("X-User"); user {
+			case "":
+				return nil, Err("auth", "no user", UnauthorizedError)
+			case "error":
+				return nil, errors.New("db down")
+			default:
+				return &principal{user}, nil
+			}
+		}),
+		Provide(func(r *http.Request) (*testTx, func(), error) {
+			calls = append(calls, "tx")
+			return &testTx{1}, func() { calls = append(calls, "tx-cleanup") }, nil
+		}),
+	)
+
+	type input struct{ Foo string }
+	handler := func(tx *testTx, ctx context.Context, p *principal, in *input) string {
+		calls = append(calls, "handler")
+		return p.Name + " " + in.Foo + " " + strconv.Itoa(tx.id)
+	}
+
+	for i, c := range []struct {
+		user     string
+		expCode  int
+		expBody  string
+		expCalls string
+		expKind  ErrorKind
+	}{
+		{"bob", 200, `"bob bar 1"` + "\n", "tx,principal,handler,tx-cleanup", OtherError},
+		{"", 400, "", "tx,principal,tx-cleanup", UnauthorizedError},
+		{"error", 500, "", "tx,principal,tx-cleanup", ServerError},
+	} {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			calls = nil
+			var o Outcome
+			h := New(
+				RequestDecoding(epcoding.JSON{}),
+				ResponseEncoding(epcoding.JSON{}),
+				AfterHook(func(r *http.Request, oc Outcome) { o = oc }),
+				testErrorHooks,
+				provs,
+			).Handle(handler)
+
+			r := httptest.NewRequest("POST", "/", strings.NewReader(`{"Foo":"bar"}`))
+			r.Header.Set("Content-Type", "application/json")
+			r.Header.Set("X-User", c.user)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != c.expCode {
+				t.Fatalf("expected code %v, got: %v", c.expCode, w.Code)
+			}
+
+			if c.expBody != "" && w.Body.String() != c.expBody {
+				t.Fatalf("expected body %v, got: %v", c.expBody, w.Body.String())
+			}
+
+			if strings.Join(calls, ",") != c.expCalls {
+				t.Fatalf("expected calls %v, got: %v", c.expCalls, calls)
+			}
+
+			if c.expKind != OtherError && Kind(o.Err) != c.expKind {
+				t.Fatalf("expected error kind %v, got: %v", c.expKind, o.Err)
+			}
+		})
+	}
+}
+
+func TestProvideOverride(t *testing.T) {
+	c := New(
+		ResponseEncoding(epcoding.JSON{}),
+		Provide(func(r *http.Request) (*principal, error) { return &principal{"foo"}, nil }),
+	)
+
+	handler := func(p *principal) string { return p.Name }
+	for i, h := range []http.Handler{
+		c.Handle(handler, Provide(func(r *http.Request) (*principal, error) { return &principal{"bar"}, nil })),
+		c.Handle(handler),
+	} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+		if exp := []string{`"bar"` + "\n", `"foo"` + "\n"}[i]; w.Body.String() != exp {
+			t.Fatalf("expected %v, got: %v", exp, w.Body.String())
+		}
+	}
+}
+
+func TestProvideValidatedAtHandle(t *testing.T) {
+	defer func() {
+		if v := recover(); v == nil || !strings.Contains(v.(string), "'*ep.testTx' is not provided") {
+			t.Fatalf("expected panic about unprovided argument, got: %v", v)
+		}
+	}()
+
+	New(
+		Provide(func(r *http.Request) (*principal, error) { return nil, nil }),
+	).Handle(func(p *principal, in struct{}, tx *testTx) {})
+}
@@ -0,0 +1,142 @@
+package ep
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"net/http"
+	"time"
+)
+
+// RequestIDConfig configures the RequestID option
+type RequestIDConfig struct {
+
+	// Header that is read from the request and echoed on the response,
+	// defaults to X-Request-ID.
+	Header string
+
+	// Generate is called to create an ID for requests that didn't provide a
+	// (valid) one, defaults to NewUUID.
+	Generate func() string
+
+	// IgnoreIncoming will cause an ID to be generated for every request, even
+	// if the client provided one. Useful when clients are not trusted.
+	IgnoreIncoming bool
+}
+
+// MaxRequestIDLength is the maximum length of an incoming request ID, longer
+// IDs are replaced by a generated one.
+const MaxRequestIDLength = 128
+
+// RequestID option assigns an ID to every request. It is read from the request
+// header or generated if the client didn't provide one. It is stored in the
+// request context such that handlers, hooks and loggers can retrieve it with
+// RequestIDFromContext, and it is echoed on the response.
+func RequestID(cfg RequestIDConfig) Option {
+	if cfg.Header == "" {
+		cfg.Header = "X-Request-ID"
+	}
+
+	if cfg.Generate == nil {
+		cfg.Generate = NewUUID
+	}
+
+	return &requestID{cfg}
+}
+
+type requestID struct{ RequestIDConfig }
+
+func (o *requestID) apply(c *Codec) {
+	c.requestID = o
+	c.addResponseHook(firstPhase, o.hook)
+}
+
+// hook is the response hook that echos the request ID
+func (o *requestID) hook(w http.ResponseWriter, r *http.Request, out interface{}) {
+	if rid := RequestIDFromContext(r.Context()); rid != "" {
+		w.Header().Set(o.Header, rid)
+	}
+}
+
+// withID returns a shallow copy of the request with its ID in the context
+func (o *requestID) withID(r *http.Request) *http.Request {
+	rid := r.Header.Get(o.Header)
+	if o.IgnoreIncoming || !validRequestID(rid) {
+		rid = o.Generate()
+	}
+
+	return r.WithContext(context.WithValue(r.Context(), requestIDKey{}, rid))
+}
+
+type requestIDKey struct{}
+
+// RequestIDFromContext returns the ID that was assigned to the request or an
+// empty string if the Codec was not configured with the RequestID option.
+func RequestIDFromContext(ctx context.Context) string {
+	rid, _ := ctx.Value(requestIDKey{}).(string)
+	return rid
+}
+
+// validRequestID checks that the incoming ID is non-empty, not too long and
+// only contains printable ASCII characters so it is safe to echo and log.
+func validRequestID(rid string) bool {
+	if rid == "" || len(rid) > MaxRequestIDLength {
+		return false
+	}
+
+	for i := 0; i < len(rid); i++ {
+		if rid[i] < 0x21 || rid[i] > 0x7e {
+			return false
+		}
+	}
+
+	return true
+}
+
+// NewUUID generates a random (version 4) UUID using crypto/rand
+func NewUUID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic("ep: failed to read random bytes: " + err.Error())
+	}
+
+	b[6] = (b[6] & 0x0f) | 0x40 // version 4
+	b[8] = (b[8] & 0x3f) | 0x80 // variant 10
+
+	var s [36]byte
+	hex.Encode(s[0:8], b[0:4])
+	s[8] = '-'
+	hex.Encode(s[9:13], b[4:6])
+	s[13] = '-'
+	hex.Encode(s[14:18], b[6:8])
+	s[18] = '-'
+	hex.Encode(s[19:23], b[8:10])
+	s[23] = '-'
+	hex.Encode(s[24:], b[10:])
+	return string(s[:])
+}
+
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// NewULID generates a ULID with the current time and randomness from
+// crypto/rand. ULIDs sort lexicographically by the time they were created.
+func NewULID() string {
+	var b [16]byte
+	binary.BigEndian.PutUint64(b[:8], uint64(time.Now().UnixMilli())<<16)
+	if _, err := rand.Read(b[6:]); err != nil {
+		panic("ep: failed to read random bytes: " + err.Error())
+	}
+
+	// encode the 128 bits as 26 characters of 5 bits, the first character
+	// only holds the 3 most significant bits.
+	hi, lo := binary.BigEndian.Uint64(b[:8]), binary.BigEndian.Uint64(b[8:])
+	var s [26]byte
+	for i := 25; i >= 0; i-- {
+		s[i] = crockford[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+
+	return string(s[:])
+}
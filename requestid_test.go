@@ -0,0 +1,90 @@
+package ep
+
+import (
+	"context"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestNewUUID(t *testing.T) {
+	exp := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	if id1, id2 := NewUUID(), NewUUID(); !exp.MatchString(id1) || id1 == id2 {
+		t.Fatalf("unexpected uuids, got: %v %v", id1, id2)
+	}
+}
+
+func TestNewULID(t *testing.T) {
+	exp := regexp.MustCompile(`^[0-7][0-9A-HJKMNP-TV-Z]{25}$`)
+	id1 := NewULID()
+	if !exp.MatchString(id1) {
+		t.Fatalf("unexpected ulid, got: %v", id1)
+	}
+
+	// the first 10 characters encode the time in milliseconds
+	if id2 := NewULID(); id1[:10] > id2[:10] || id1 == id2 {
+		t.Fatalf("ulids should sort by time, got: %v %v", id1, id2)
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	for i, c := range []struct {
+		cfg   RequestIDConfig
+		hdr   string
+		inID  string
+		expID string
+	}{
+		{cfg: RequestIDConfig{}, expID: "gen"},
+		{cfg: RequestIDConfig{}, hdr: "X-Request-ID", inID: "abc", expID: "abc"},
+		{cfg: RequestIDConfig{}, hdr: "X-Request-ID", inID: "a b", expID: "gen"},
+		{cfg: RequestIDConfig{}, hdr: "X-Request-ID", inID: strings.Repeat("a", MaxRequestIDLength+1), expID: "gen"},
+		{cfg: RequestIDConfig{IgnoreIncoming: true}, hdr: "X-Request-ID", inID: "abc", expID: "gen"},
+		{cfg: RequestIDConfig{Header: "X-Trace"}, hdr: "X-Trace", inID: "abc", expID: "abc"},
+		{cfg: RequestIDConfig{Header: "X-Trace"}, hdr: "X-Request-ID", inID: "abc", expID: "gen"},
+	} {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			c.cfg.Generate = func() string { return "gen" }
+
+			r := httptest.NewRequest("GET", "/", nil)
+			if c.hdr != "" {
+				r.Header.Set(c.hdr, c.inID)
+			}
+
+			var ctxID string
+			w := httptest.NewRecorder()
+			New(RequestID(c.cfg)).Handle(func(ctx context.Context) {
+				ctxID = RequestIDFromContext(ctx)
+			}).ServeHTTP(w, r)
+
+			if ctxID != c.expID {
+				t.Fatalf("unexpected id in context, got: %v", ctxID)
+			}
+
+			hdr := c.cfg.Header
+			if hdr == "" {
+				hdr = "X-Request-ID"
+			}
+
+			if w.Header().Get(hdr) != c.expID {
+				t.Fatalf("unexpected id in response, got: %v", w.Header().Get(hdr))
+			}
+		})
+	}
+}
+
+func TestRequestIDDefaultGenerator(t *testing.T) {
+	var ctxID string
+	New(RequestID(RequestIDConfig{})).Handle(func(ctx context.Context) {
+		ctxID = RequestIDFromContext(ctx)
+	}).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	if len(ctxID) != 36 {
+		t.Fatalf("should have generated uuid, got: %v", ctxID)
+	}
+
+	if RequestIDFromContext(context.Background()) != "" {
+		t.Fatalf("should be empty without option")
+	}
+}
@@ -1,10 +1,14 @@
 package ep
 
 import (
+	"bytes"
+	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"reflect"
+	"runtime/debug"
+	"time"
 
 	"github.com/advanderveer/ep/epcoding"
 )
@@ -22,9 +26,12 @@ type response struct {
 	http.ResponseWriter
 	req *http.Request
 
-	reqHooks []RequestHook
-	resHooks []ResponseHook
-	errHooks []ErrorHook
+	reqHooks     []RequestHook
+	decodedHooks []RequestHook
+	resHooks     []ResponseHook
+	errHooks     []RequestErrorHook
+	panicHooks   []PanicHook
+	afterHooks   []AfterHook
 
 	enc epcoding.Encoder
 	dec epcoding.Decoder
@@ -33,8 +40,29 @@ type response struct {
 	encNegotiateErr error
 	decNegotiateErr error
 	wroteHeader     bool
+	status          int
 	runningReqHooks bool
+	ranResHooks     bool
+	stopResHooks    func()
+	currentHook     ResponseHook
+	hookConflicts   HookConflictMode
 	currentOutput   interface{}
+
+	bufferOutputs bool
+	buffering     *bytes.Buffer
+	currentBody   []byte
+
+	debug    *DebugInfo
+	logs     *slog.Logger
+	tracer   Tracer
+	rootSpan Span
+	timings  *ServerTimings
+
+	started    time.Time
+	written    int64
+	lastOutput interface{}
+	lastErr    error
+	cleanups   []func()
 }
 
 func newResponse(
@@ -53,19 +81,41 @@ func newResponse(
 
 		reqHooks: reqh,
 		resHooks: resh,
-		errHooks: errh,
 	}
 
-	// any failure to negotiate is only important if we actually wanna decode
-	// something during a call to bind
-	res.dec, res.decNegotiateErr = negotiateDecoder(res.req, decs)
+	for _, h := range errh {
+		res.errHooks = append(res.errHooks, h.withRequest())
+	}
+
+	res.negotiate(decs, encs)
+	return res
+}
+
+// negotiate the decoder and encoder for the response
+func (res *response) negotiate(decs []epcoding.Decoding, encs []epcoding.Encoding) {
+	span := res.span("ep.negotiate")
+	defer span.End()
+	defer res.timings.Start("negotiate", "")()
 
 	// Failing to negotiate an encoder is only important when we know for sure
 	// that it will be used during a call to render. The user might decide to
 	// write to the response itself, or the API doesn't need encoding at all.
 	// So we keep the error in the response to be reported later.
 	res.enc, res.encContentType, res.encNegotiateErr = negotiateEncoder(res.req, res, encs)
-	return res
+	if res.encNegotiateErr != nil {
+		res.logger().Debug("failed to negotiate encoder", ErrorAttrs(res.encNegotiateErr)...)
+		span.RecordError(res.encNegotiateErr)
+	}
+
+	// any failure to negotiate is only important if we actually wanna decode
+	// something during a call to bind. It is negotiated last such that a
+	// panic during decoder negotiation can still be rendered with the encoder.
+	res.dec, res.decNegotiateErr = negotiateDecoder(res.req, decs)
+	if res.decNegotiateErr != nil {
+		res.logger().Debug("failed to negotiate decoder", ErrorAttrs(res.decNegotiateErr)...)
+		span.RecordError(res.decNegotiateErr)
+	}
+	res.debug.coders(res)
 }
 
 // NewResponse initializes a ResponseWriter
@@ -85,30 +135,35 @@ func NewResponse(
 // method will make an implicit call to WriteHeader which will call any hooks
 // in order.
 func (res *response) Write(b []byte) (int, error) {
+	if res.buffering != nil {
+		return res.buffering.Write(b)
+	}
+
 	if !res.wroteHeader {
 		res.WriteHeader(http.StatusOK)
 	}
 
-	return res.ResponseWriter.Write(b)
+	// some status codes don't allow a body, hooks might have written such a
+	// status (e.g. 304) so we silently discard what was meant for the body.
+	if !bodyAllowedForStatus(res.status) {
+		return len(b), nil
+	}
+
+	n, err := res.ResponseWriter.Write(b)
+	res.written += int64(n)
+	return n, err
 }
 
 // WriteHeader will call any configured hooks and sends the http response header
 // with the resulting status code.
 func (res *response) WriteHeader(statusCode int) {
 	if res.wroteHeader {
+		res.discardedStatus(statusCode)
 		return
 	}
 
-	if !res.runningReqHooks {
-		res.runningReqHooks = true
-		defer func() { res.runningReqHooks = false }()
-		for _, h := range res.resHooks {
-			h(
-				res,
-				res.req,
-				res.currentOutput, // might be nil
-			)
-		}
+	if !res.runningReqHooks && !res.ranResHooks {
+		res.responseHooks()
 	}
 
 	// this check ensures that if any hooks called writeHeader we won't be
@@ -117,13 +172,65 @@ func (res *response) WriteHeader(statusCode int) {
 		return
 	}
 
+	if res.stopResHooks != nil {
+		res.stopResHooks()
+	}
+
+	res.timings.writeHeader(res.Header())
 	res.ResponseWriter.WriteHeader(statusCode)
 	res.wroteHeader = true
+	res.status = statusCode
+}
+
+// responseHooks calls the response hooks in order
+func (res *response) responseHooks() {
+	res.runningReqHooks = true
+	defer func() { res.runningReqHooks = false }()
+
+	span := res.span("ep.response_hooks")
+	defer span.End()
+	if len(res.resHooks) > 0 && res.timings != nil {
+
+		// a hook might write the header itself, so the timing is stopped
+		// before the header is written.
+		stop, stopped := res.timings.Start("reshooks", ""), false
+		res.stopResHooks = func() {
+			if !stopped {
+				stop()
+				stopped = true
+			}
+		}
+
+		defer func() { res.stopResHooks(); res.stopResHooks = nil }()
+	}
+
+	defer func() { res.currentHook = nil }()
+	for _, h := range res.resHooks {
+		res.debug.hook("response", h)
+		res.currentHook = h
+
+		// if another hook already wrote the header, any modification by this
+		// hook will be discarded.
+		var before http.Header
+		if res.hookConflicts != IgnoreHookConflicts && res.wroteHeader {
+			before = res.Header().Clone()
+		}
+
+		h(
+			res,
+			res.req,
+			res.currentOutput, // might be nil
+		)
+
+		if before != nil && !headerEqual(before, res.Header()) {
+			res.hookConflict(h, "modified the header after it was written")
+		}
+	}
 }
 
 // Bind will decode the next value from the request into the input 'in'
 func (res *response) Bind(in interface{}) bool {
-	ok, err := res.bind(in)
+	ok, err := res.bind(in, true)
 	if err != nil {
 		res.Render(nil, err)
 		return false
@@ -132,23 +239,75 @@ func (res *response) Bind(in interface{}) bool {
 	return ok
 }
 
-func (res *response) bind(in interface{}) (ok bool, err error) {
-	const op Op = "response.bind"
+// bindInputs binds each of the inputs, in order. The request hooks are called
+// for every input but the body is only decoded into the first input that
+// doesn't skip decoding, the others are expected to be bound by the hooks.
+func (res *response) bindInputs(ins []reflect.Value) bool {
+	decode := true
+	for _, inv := range ins {
+		in := inv.Interface()
+		ok, err := res.bind(in, decode)
+		if err != nil {
+			res.Render(nil, err)
+			return false
+		} else if !ok {
+			return false
+		}
 
-	for _, h := range res.reqHooks {
-		if err := h(res.req, in); err != nil {
-			return false, Err(op, "request hook failed", err, RequestHookError)
+		if !skipsDecode(in) {
+			decode = false
 		}
 	}
 
-	// if the input is nil or has an SkipDecode() method we skip decoding
+	return true
+}
+
+// skipsDecode returns whether the input 'in' is not decoded from the body
+func skipsDecode(in interface{}) bool {
 	switch vt := in.(type) {
 	case nil:
-		return true, nil
+		return true
 	case interface{ SkipDecode() bool }:
-		if vt.SkipDecode() {
-			return true, nil
+		return vt.SkipDecode()
+	}
+
+	return false
+}
+
+func (res *response) bind(in interface{}, decode bool) (ok bool, err error) {
+	const op Op = "response.bind"
+
+	span := res.span("ep.bind")
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
 		}
+
+		span.End()
+	}()
+
+	if err = res.requestHooks(res.reqHooks, in); err != nil {
+		return false, Err(op, "request hook failed", err, RequestHookError)
+	}
+
+	if ok, err = res.decode(in, decode); !ok || err != nil {
+		return ok, err
+	}
+
+	if err = res.requestHooks(res.decodedHooks, in); err != nil {
+		return false, Err(op, "request hook failed", err, RequestHookError)
+	}
+
+	return true, nil
+}
+
+// decode the request body into the input 'in', unless 'decode' is false
+func (res *response) decode(in interface{}, decode bool) (ok bool, err error) {
+	const op Op = "response.bind"
+
+	// if the input is nil or has an SkipDecode() method we skip decoding
+	if !decode || skipsDecode(in) {
+		return true, nil
 	}
 
 	if res.decNegotiateErr != nil {
@@ -159,7 +318,9 @@ func (res *response) bind(in interface{}) (ok bool, err error) {
 		return true, nil
 	}
 
+	stop := res.timings.Start("decode", "")
 	err = res.dec.Decode(in)
+	stop()
 	if err == io.EOF {
 		return false, nil
 	} else if err != nil {
@@ -169,6 +330,22 @@ func (res *response) bind(in interface{}) (ok bool, err error) {
 	return true, nil
 }
 
+// requestHooks calls the request hooks in order until one fails
+func (res *response) requestHooks(hooks []RequestHook, in interface{}) error {
+	if len(hooks) > 0 {
+		defer res.timings.Start("reqhooks", "")()
+	}
+
+	for _, h := range hooks {
+		res.debug.hook("request", h)
+		if err := h(res.req, in); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // Render will encode the first non-nil argument into the response body. If any
 // of the arguments is an error, it takes precedence and is rendered instead.
 func (res *response) Render(outs ...interface{}) {
@@ -194,8 +371,16 @@ func (res *response) Render(outs ...interface{}) {
 		}
 	}
 
+	if errv, ok := out.(error); ok {
+		res.lastErr = errv
+	}
+
 	err := res.render(out) // first pass
 	if err != nil {
+		res.lastErr = err
+		res.logger().Log(res.req.Context(), errorLevel(err), "failed to render output",
+			append(ErrorAttrs(err), slog.String("content_type", res.encContentType))...)
+
 		err = res.render(err) // second pass
 		if err != nil {
 			panic("ep/response: failed to render: " + err.Error())
@@ -210,17 +395,17 @@ func (res *response) render(v interface{}) (err error) {
 	if errv, ok := v.(error); ok {
 
 		// If there was an error but no hooks to turn it into an output
-		// we log this situation to the default logger so the user knows
-		// whats going on.
+		// we log this situation so the user knows whats going on.
 		if len(res.errHooks) < 1 {
-			log.Printf("ep: no error hooks to render error: %v", errv)
+			res.logger().Warn("no error hooks to render error", ErrorAttrs(errv)...)
 		}
 
 		// Error hooks are responsible for turning any error into an output
 		// that can be rendered by the encoder.
 		// var foundErrOutput bool
 		for _, h := range res.errHooks {
-			if eout := h(errv); eout != nil {
+			res.debug.hook("error", h)
+			if eout := h(res.req, res.encContentType, errv); eout != nil {
 				v = eout
 				break
 			}
@@ -230,7 +415,7 @@ func (res *response) render(v interface{}) (err error) {
 	// WriteHeader needs access to the output value but the interface refrains
 	// us from passing it as an argument so we need to set it as an temporary
 	// struct member.
-	res.currentOutput = v
+	res.currentOutput, res.lastOutput = v, v
 	defer func() { res.currentOutput = nil }()
 
 	// If the value turns out to be nil or implements the Empty() method, we won't
@@ -247,6 +432,12 @@ func (res *response) render(v interface{}) (err error) {
 		}
 	}
 
+	// If a hook already wrote a status that doesn't allow a body there is no
+	// need to spend any effort on encoding.
+	if res.wroteHeader && !bodyAllowedForStatus(res.status) {
+		return nil
+	}
+
 	// We for sure have a value to encode, so if we had any issues with getting
 	// an encoder we will stop here
 	if res.encNegotiateErr != nil {
@@ -267,9 +458,46 @@ func (res *response) render(v interface{}) (err error) {
 		res.Header().Set("X-Content-Type-Options", "nosniff")
 	}
 
-	err = res.enc.Encode(v)
+	// Without buffering the response hooks would only be called on the first
+	// write of the encoder. For outputs that provide validators they are called
+	// before encoding instead, such that a 304 is written without encoding.
+	var hooked http.Header
+	if !res.bufferOutputs && hasValidators(v) {
+		hooked = res.Header().Clone()
+		res.responseHooks()
+		if res.wroteHeader && !bodyAllowedForStatus(res.status) {
+			return nil
+		}
+
+		res.ranResHooks = true
+		defer func() { res.ranResHooks = false }()
+	}
+
+	// With server timings the output is also encoded into memory first, such
+	// that the encoding is measured before the header is written.
+	buffered := res.bufferOutputs || res.timings != nil
+	if buffered {
+		res.buffering = bytes.NewBuffer(nil)
+		defer func() { res.buffering, res.currentBody = nil, nil }()
+	}
+
+	err = res.encode(v)
+	var body []byte
+	if buffered {
+		body, res.buffering = res.buffering.Bytes(), nil
+		if res.bufferOutputs {
+			res.currentBody = body
+		}
+	}
+
 	if err != nil {
 
+		// If the hooks were called before encoding but nothing was written
+		// their headers should not end up on the response of the error.
+		if hooked != nil && !res.wroteHeader {
+			resetHeader(res.Header(), hooked)
+		}
+
 		// If we just added the content-type header but the encoding fails we
 		// reset it such that a subsequent call to render can set it again.
 		if ctFromEnc {
@@ -280,25 +508,178 @@ func (res *response) render(v interface{}) (err error) {
 		return Err(op, "response body encoder failed", err, EncoderError)
 	}
 
+	// with buffering the body is only written after it was fully encoded, such
+	// that response hooks can inspect it.
+	if buffered {
+		if _, err = res.Write(body); err != nil {
+			return Err(op, "failed to write buffered body", err, EncoderError)
+		}
+	}
+
 	return
 }
 
+// encode the value with the negotiated encoder
+func (res *response) encode(v interface{}) (err error) {
+	span := res.span("ep.encode")
+	span.SetAttribute("content_type", res.encContentType)
+	defer res.timings.Start("encode", "")()
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+		}
+
+		span.End()
+	}()
+
+	return res.enc.Encode(v)
+}
+
+// EncodedBody returns the encoded body of the output that is currently being
+// rendered. It is meant to be called by response hooks and only returns a
+// non-nil value if the Codec was configured with the BufferOutputs option.
+func EncodedBody(w http.ResponseWriter) []byte {
+	res, ok := w.(*response)
+	if !ok {
+		return nil
+	}
+
+	return res.currentBody
+}
+
+// hasValidators reports whether the output provides validators for
+// conditional requests.
+func hasValidators(v interface{}) bool {
+	switch v.(type) {
+	case interface{ ETag() string }, interface{ LastModified() time.Time }:
+		return true
+	}
+
+	return false
+}
+
+// resetHeader resets the header 'h' to the values in 'to'
+func resetHeader(h, to http.Header) {
+	for k := range h {
+		if _, ok := to[k]; !ok {
+			delete(h, k)
+		}
+	}
+
+	for k, v := range to {
+		h[k] = v
+	}
+}
+
+// bodyAllowedForStatus reports whether a given response status code permits a
+// body. See RFC 7230, section 3.3.
+func bodyAllowedForStatus(status int) bool {
+	switch {
+	case status >= 100 && status <= 199:
+		return false
+	case status == http.StatusNoContent:
+		return false
+	case status == http.StatusNotModified:
+		return false
+	}
+
+	return true
+}
+
+// after calls the cleanups of providers and the after hooks with the outcome
+// of the request
+func (res *response) after() {
+	res.cleanup()
+
+	if res.timings != nil {
+		if res.wroteHeader {
+			res.timings.writeTrailer(res.Header())
+		} else {
+			res.timings.writeHeader(res.Header())
+		}
+	}
+
+	if len(res.afterHooks) < 1 && res.rootSpan == nil {
+		return
+	}
+
+	o := Outcome{
+		Status:   res.status,
+		Written:  res.written,
+		Duration: time.Since(res.started),
+		Output:   res.lastOutput,
+		Err:      res.lastErr,
+
+		ContentType: res.encContentType,
+	}
+
+	if !res.wroteHeader {
+		o.Status = http.StatusOK
+	}
+
+	if res.rootSpan != nil {
+		res.rootSpan.SetAttribute("http.status_code", o.Status)
+		if o.Err != nil {
+			res.rootSpan.RecordError(o.Err)
+		}
+
+		res.rootSpan.End()
+	}
+
+	for _, h := range res.afterHooks {
+		res.debug.hook("after", h)
+		h(res.req, o)
+	}
+}
+
+// Recover from a panic and render it as a server error. It should be deferred
+// before any code that might panic. Panics with http.ErrAbortHandler are
+// re-panicked such that the server can abort the response as it expects.
 func (res *response) Recover() {
-	r := recover()
-	if r == nil {
+	v := recover()
+	if v == nil {
 		return
 	}
 
-	var perr error
-	switch rt := r.(type) {
+	if v == http.ErrAbortHandler {
+		panic(v)
+	}
+
+	res.recovered(v, debug.Stack())
+}
+
+// recovered renders the recovered panic value 'v'
+func (res *response) recovered(v interface{}, stack []byte) {
+	const op Op = "response.Recover"
+
+	var perr *Error
+	switch vt := v.(type) {
 	case error:
-		perr = Err(Op("response.Recover"), "error", ServerError, rt)
+		perr = Err(op, "error", ServerError, vt)
 	case string:
-		perr = Err(Op("response.Recover"), rt, ServerError)
+		perr = Err(op, vt, ServerError)
 	default:
-		perr = Err(Op("response.Recover"), "unknown panic", ServerError)
-		return
+		perr = Err(op, "unknown panic", ServerError, fmt.Errorf("%v", vt))
 	}
 
+	perr.stack = stack
+	res.logger().Error("recovered panic", slog.Any("panic", v), slog.String("stack", string(stack)))
+	for _, h := range res.panicHooks {
+		res.debug.hook("panic", h)
+		h(res.req, v, stack)
+	}
+
+	// rendering the panic might panic itself, for example if a response hook
+	// keeps panicking. As a last resort we write a bare server error.
+	defer func() {
+		if v := recover(); v != nil {
+			res.logger().Error("failed to render recovered panic", slog.Any("panic", v))
+			if !res.wroteHeader {
+				res.ResponseWriter.WriteHeader(http.StatusInternalServerError)
+				res.wroteHeader, res.status = true, http.StatusInternalServerError
+			}
+		}
+	}()
+
 	res.Render(nil, perr)
 }
@@ -57,7 +57,7 @@ func TestNegotiate(t *testing.T) {
 			w := httptest.NewRecorder()
 			a := New(c.opts...)
 
-			res := newResponse(w, r, a.reqHooks, a.resHooks, a.errHooks, a.decodings, a.encodings)
+			res := newResponse(w, r, a.reqHooks, a.resHooks, nil, a.decodings, a.encodings)
 			if !errors.Is(res.decNegotiateErr, c.expErr) {
 				t.Fatalf("expected error %#v, got: %#v", c.expErr, res.decNegotiateErr)
 			}
@@ -445,7 +445,7 @@ func TestPrivateBind(t *testing.T) {
 
 			res := newResponse(w, r, c.hooks, nil, nil, c.decs, nil)
 
-			ok, err := res.bind(c.in)
+			ok, err := res.bind(c.in, true)
 			if !errors.Is(err, c.expErr) {
 				t.Fatalf("expected error %#v, got: %#v", c.expErr, err)
 			}
@@ -475,7 +475,7 @@ func TestPrivateBindSequential(t *testing.T) {
 
 	for i := 0; i < 100; i++ {
 		var in struct{ Foo string }
-		ok, err := res.bind(&in)
+		ok, err := res.bind(&in, true)
 		if err != nil {
 			t.Fatalf("unexpected, got: %v", err)
 		}
@@ -612,6 +612,92 @@ func TestRecover(t *testing.T) {
 	}
 }
 
+func TestRecoverRendersAnyValue(t *testing.T) {
+	for i, v := range []interface{}{1, struct{}{}, []string{"foo"}} {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			w := httptest.NewRecorder()
+
+			h := func(err error) interface{} { return struct{ Message string }{err.Error()} }
+			res := newResponse(w, r, nil, []ResponseHook{func(w http.ResponseWriter, r *http.Request, out interface{}) {
+				w.WriteHeader(http.StatusInternalServerError)
+			}}, []ErrorHook{h}, nil, []epcoding.Encoding{epcoding.JSON{}})
+
+			func() {
+				defer res.Recover()
+				panic(v)
+			}()
+
+			if w.Code != http.StatusInternalServerError ||
+				!strings.HasPrefix(w.Body.String(), `{"Message":"response.Recover: unknown panic: `) {
+				t.Fatalf("unexpected response, got: %v %v", w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestRecoverAbortHandler(t *testing.T) {
+	defer func() {
+		if r := recover(); r != http.ErrAbortHandler {
+			t.Errorf("should have re-panicked with abort handler, got: %v", r)
+		}
+	}()
+
+	r := httptest.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	res := newResponse(w, r, nil, nil, nil, nil, []epcoding.Encoding{epcoding.JSON{}})
+
+	defer res.Recover()
+	panic(http.ErrAbortHandler)
+}
+
+func TestRecoverStackAndPanicHook(t *testing.T) {
+	var rerr error
+	h := func(err error) interface{} {
+		rerr = err
+		return nil
+	}
+
+	var hookv interface{}
+	var hookStack []byte
+	r := httptest.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	res := newResponse(w, r, nil, nil, []ErrorHook{h}, nil, []epcoding.Encoding{epcoding.JSON{}})
+	res.panicHooks = []PanicHook{func(r *http.Request, v interface{}, stack []byte) {
+		hookv, hookStack = v, stack
+	}}
+
+	func() {
+		defer res.Recover()
+		panic("foo")
+	}()
+
+	if hookv != "foo" || !bytes.Contains(hookStack, []byte("TestRecoverStackAndPanicHook")) {
+		t.Fatalf("unexpected panic hook call, got: %v %s", hookv, hookStack)
+	}
+
+	if !bytes.Equal(Stack(rerr), hookStack) {
+		t.Fatalf("error should carry the stack, got: %s", Stack(rerr))
+	}
+}
+
+func TestRecoverPanickingResponseHook(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+
+	h := func(w http.ResponseWriter, r *http.Request, out interface{}) { panic("foo") }
+	res := newResponse(w, r, nil, []ResponseHook{h}, nil, nil, []epcoding.Encoding{epcoding.JSON{}})
+
+	func() {
+		defer res.Recover()
+		res.Render(struct{}{})
+	}()
+
+	if w.Code != http.StatusInternalServerError || w.Body.Len() != 0 {
+		t.Fatalf("should have written bare server error, got: %v %v", w.Code, w.Body.String())
+	}
+}
+
 func TestPanicInResponseHook(t *testing.T) {
 	r := httptest.NewRequest("GET", "/", nil)
 	w := httptest.NewRecorder()
@@ -661,3 +747,61 @@ func shouldRenderErr(t *testing.T, target error) func(error) interface{} {
 		return nil
 	}
 }
+
+func TestWriteWithoutBodyAllowed(t *testing.T) {
+	hook := func(w http.ResponseWriter, r *http.Request, out interface{}) {
+		w.WriteHeader(http.StatusNotModified)
+	}
+
+	r := httptest.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	res := newResponse(w, r, nil, []ResponseHook{hook}, nil, nil, []epcoding.Encoding{epcoding.JSON{}})
+
+	n, err := res.Write([]byte("foo"))
+	if n != 3 || err != nil {
+		t.Fatalf("unexpected, got: %d, %v", n, err)
+	}
+
+	err = res.render("bar")
+	if err != nil {
+		t.Fatalf("unexpected, got: %v", err)
+	}
+
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Fatalf("unexpected, got: %d, %s", w.Code, w.Body.String())
+	}
+}
+
+func TestRenderBuffered(t *testing.T) {
+	var body []byte
+	hook := func(w http.ResponseWriter, r *http.Request, out interface{}) {
+		body = append(body, EncodedBody(w)...)
+	}
+
+	r := httptest.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	res := newResponse(w, r, nil, []ResponseHook{hook}, nil, nil, []epcoding.Encoding{epcoding.JSON{}})
+	res.bufferOutputs = true
+
+	err := res.render(make(chan struct{}))
+	if !errors.Is(err, Err(Op("response.render"), EncoderError)) {
+		t.Fatalf("expected encoder error, got: %v", err)
+	}
+
+	if res.wroteHeader {
+		t.Fatalf("header should not be written when buffered encoding fails")
+	}
+
+	err = res.render("foo")
+	if err != nil {
+		t.Fatalf("unexpected, got: %v", err)
+	}
+
+	if string(body) != `"foo"`+"\n" || w.Body.String() != `"foo"`+"\n" {
+		t.Fatalf("unexpected, got: %s, %s", body, w.Body.String())
+	}
+
+	if EncodedBody(w) != nil || EncodedBody(res) != nil {
+		t.Fatalf("encoded body should only be available while rendering")
+	}
+}
@@ -0,0 +1,155 @@
+package ep
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Router dispatches requests to handlers that are registered per method and
+// path pattern. Handlers are created by the Codec so they are configured
+// identically. Patterns are matched by an http.ServeMux while the router takes
+// care of the method: it answers OPTIONS requests and responds with 405 Method
+// Not Allowed (and an Allow header) for methods that are not registered.
+// Requests that match no pattern are rendered as a NotFoundError with a 404
+// status. Both errors are rendered through the Codec's error hooks and
+// encodings, hooks can still write another status.
+type Router struct {
+	codec    *Codec
+	mux      *http.ServeMux
+	patterns map[string]*routeMethods
+	routes   []Route
+
+	notFound         http.Handler
+	methodNotAllowed http.Handler
+}
+
+// Route describes a registered handler
+type Route struct {
+	Method  string
+	Pattern string
+	Handler string // name of the registered function
+}
+
+// NewRouter creates a router that creates its handlers with the Codec
+func NewRouter(c *Codec) *Router {
+	const op Op = "router.ServeHTTP"
+
+	return &Router{
+		codec:    c,
+		mux:      http.NewServeMux(),
+		patterns: make(map[string]*routeMethods),
+
+		notFound: c.Handle(func() error {
+			return Err(op, "no route matches the request", NotFoundError)
+		}, finalStatus(http.StatusNotFound)),
+		methodNotAllowed: c.Handle(func() error {
+			return Err(op, "route doesn't support the method", MethodNotAllowedError)
+		}, finalStatus(http.StatusMethodNotAllowed)),
+	}
+}
+
+// Route registers the function 'f' as the handler for requests with the method
+// and path pattern. The function and options can be anything that is accepted
+// by the Codec's Handle method. It panics if the method was already registered
+// for the pattern.
+func (rt *Router) Route(method, pattern string, f interface{}, opts ...Option) {
+	method = strings.ToUpper(method)
+
+	rm, ok := rt.patterns[pattern]
+	if !ok {
+		rm = &routeMethods{rt: rt, handlers: make(map[string]http.Handler)}
+		rm.options = rt.codec.Handle(func(w ResponseWriter, r *http.Request) {
+			w.Header().Set("Allow", rm.allow)
+			w.WriteHeader(http.StatusNoContent)
+		})
+
+		rt.mux.Handle(pattern, rm)
+		rt.patterns[pattern] = rm
+	}
+
+	if _, ok := rm.handlers[method]; ok {
+		panic("ep: route already registered: " + method + " " + pattern)
+	}
+
+	rm.handlers[method] = rt.codec.Handle(f, opts...)
+	rm.methods = append(rm.methods, method)
+	rm.allow = allowHeader(rm.methods)
+	rt.routes = append(rt.routes, Route{Method: method, Pattern: pattern, Handler: funcName(f)})
+}
+
+// Routes returns the registered routes, sorted by pattern
+func (rt *Router) Routes() []Route {
+	routes := append([]Route(nil), rt.routes...)
+	sort.SliceStable(routes, func(i, j int) bool { return routes[i].Pattern < routes[j].Pattern })
+	return routes
+}
+
+// ServeHTTP dispatches the request to the handler of the matching route
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if _, pattern := rt.mux.Handler(r); pattern == "" {
+		rt.notFound.ServeHTTP(w, r)
+		return
+	}
+
+	rt.mux.ServeHTTP(w, r)
+}
+
+// routeMethods holds the handlers of a single pattern
+type routeMethods struct {
+	rt       *Router
+	handlers map[string]http.Handler
+	methods  []string
+	allow    string
+	options  http.Handler
+}
+
+func (rm *routeMethods) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h, ok := rm.handlers[r.Method]
+	if !ok && r.Method == http.MethodHead {
+		h, ok = rm.handlers[http.MethodGet]
+	}
+
+	switch {
+	case ok:
+		h.ServeHTTP(w, r)
+	case r.Method == http.MethodOptions:
+		rm.options.ServeHTTP(w, r)
+	default:
+		w.Header().Set("Allow", rm.allow)
+		rm.rt.methodNotAllowed.ServeHTTP(w, r)
+	}
+}
+
+// finalStatus option writes the status 'code' if no other hook wrote the header
+func finalStatus(code int) Option {
+	return PhasedResponseHook(FinalizePhase, func(w http.ResponseWriter, r *http.Request, out interface{}) {
+		w.WriteHeader(code)
+	})
+}
+
+// allowHeader formats the Allow header for the registered methods. HEAD is
+// allowed if GET is and OPTIONS is always allowed.
+func allowHeader(methods []string) string {
+	allow := append([]string(nil), methods...)
+	has := func(m string) bool {
+		for _, a := range allow {
+			if a == m {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	if has(http.MethodGet) && !has(http.MethodHead) {
+		allow = append(allow, http.MethodHead)
+	}
+
+	if !has(http.MethodOptions) {
+		allow = append(allow, http.MethodOptions)
+	}
+
+	sort.Strings(allow)
+	return strings.Join(allow, ", ")
+}
@@ -0,0 +1,94 @@
+package ep
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/advanderveer/ep/epcoding"
+)
+
+func TestRouter(t *testing.T) {
+	errh := func(err error) interface{} {
+		switch Kind(err) {
+		case NotFoundError:
+			return struct{ Message string }{"not found"}
+		case MethodNotAllowedError:
+			return struct{ Message string }{"method not allowed"}
+		}
+
+		return nil
+	}
+
+	rt := NewRouter(New(
+		ResponseEncoding(epcoding.JSON{}),
+		ErrorHook(errh),
+	))
+
+	rt.Route("get", "/ideas", func() interface{} { return []string{"foo"} })
+	rt.Route("POST", "/ideas", func() {})
+	rt.Route("DELETE", "/ideas/", func() {})
+
+	for i, c := range []struct {
+		method   string
+		path     string
+		expCode  int
+		expBody  string
+		expAllow string
+	}{
+		{method: "GET", path: "/ideas", expCode: 200, expBody: `["foo"]` + "\n"},
+		{method: "HEAD", path: "/ideas", expCode: 200, expBody: `["foo"]` + "\n"},
+		{method: "POST", path: "/ideas", expCode: 200},
+		{method: "DELETE", path: "/ideas/foo", expCode: 200},
+		{method: "PUT", path: "/ideas", expCode: 405, expBody: `{"Message":"method not allowed"}` + "\n", expAllow: "GET, HEAD, OPTIONS, POST"},
+		{method: "OPTIONS", path: "/ideas", expCode: 204, expAllow: "GET, HEAD, OPTIONS, POST"},
+		{method: "OPTIONS", path: "/ideas/foo", expCode: 204, expAllow: "DELETE, OPTIONS"},
+		{method: "GET", path: "/bogus", expCode: 404, expBody: `{"Message":"not found"}` + "\n"},
+	} {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			w := httptest.NewRecorder()
+			rt.ServeHTTP(w, httptest.NewRequest(c.method, c.path, nil))
+
+			if w.Code != c.expCode {
+				t.Fatalf("expected code %d, got: %d", c.expCode, w.Code)
+			}
+
+			if w.Body.String() != c.expBody {
+				t.Fatalf("expected body %s, got: %s", c.expBody, w.Body.String())
+			}
+
+			if w.Header().Get("Allow") != c.expAllow {
+				t.Fatalf("expected allow %s, got: %s", c.expAllow, w.Header().Get("Allow"))
+			}
+		})
+	}
+}
+
+func TestRouterRoutes(t *testing.T) {
+	rt := NewRouter(New())
+	rt.Route("POST", "/b", func() error { return errors.New("foo") })
+	rt.Route("GET", "/a", TestRouterRoutes)
+	rt.Route("GET", "/b", func(w ResponseWriter, r *http.Request) {})
+
+	routes := rt.Routes()
+	if len(routes) != 3 ||
+		routes[0] != (Route{"GET", "/a", "ep.TestRouterRoutes"}) ||
+		routes[1].Method != "POST" || routes[1].Pattern != "/b" ||
+		routes[2].Method != "GET" || routes[2].Pattern != "/b" {
+		t.Fatalf("unexpected routes, got: %v", routes)
+	}
+}
+
+func TestRouterDuplicateRoute(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("The code did not panic")
+		}
+	}()
+
+	rt := NewRouter(New())
+	rt.Route("GET", "/", func() {})
+	rt.Route("GET", "/", func() {})
+}
@@ -0,0 +1,136 @@
+package ep
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ServerTiming option measures the phases of handling each request and reports
+// them in the Server-Timing header: negotiate, reqhooks, decode, call,
+// reshooks and encode. Rendered outputs are encoded into memory first such
+// that the encoding is reported in the header. Phases that complete after the
+// header was written (e.g. a handler that streams the body itself) are
+// reported in a Server-Timing trailer instead, which clients only receive if
+// the body was sent in chunks.
+//
+// Handlers can add their own timings with ServerTimingsFromContext. Timings
+// reveal how the server spends its time so it should only be enabled if that
+// is acceptable.
+func ServerTiming() Option {
+	return serverTiming{}
+}
+
+type serverTiming struct{}
+
+func (o serverTiming) apply(c *Codec) {
+	c.serverTiming = true
+}
+
+// ServerTimings collects the timings of a request
+type ServerTimings struct {
+	mu      sync.Mutex
+	entries []serverTimingEntry
+	sent    int // nr of entries that were written as a header or trailer
+}
+
+type serverTimingEntry struct {
+	name, desc string
+	dur        time.Duration
+}
+
+// Add a timing with a name and an optional description. Durations of timings
+// with the same name are added together. It is safe to call on a nil value.
+func (st *ServerTimings) Add(name, desc string, d time.Duration) {
+	if st == nil {
+		return
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	for i := st.sent; i < len(st.entries); i++ {
+		if st.entries[i].name == name {
+			st.entries[i].dur += d
+			return
+		}
+	}
+
+	st.entries = append(st.entries, serverTimingEntry{name, desc, d})
+}
+
+// Start measures a timing until the returned function is called. It is safe
+// to call on a nil value.
+func (st *ServerTimings) Start(name, desc string) (stop func()) {
+	if st == nil {
+		return func() {}
+	}
+
+	t0 := time.Now()
+	return func() { st.Add(name, desc, time.Since(t0)) }
+}
+
+// flush formats the timings that were not yet sent
+func (st *ServerTimings) flush() string {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	var b strings.Builder
+	for _, e := range st.entries[st.sent:] {
+		if b.Len() > 0 {
+			b.WriteString(", ")
+		}
+
+		b.WriteString(e.name)
+		b.WriteString(";dur=")
+		b.WriteString(strconv.FormatFloat(float64(e.dur)/float64(time.Millisecond), 'f', 3, 64))
+		if e.desc != "" {
+			b.WriteString(";desc=")
+			b.WriteString(strconv.Quote(e.desc))
+		}
+	}
+
+	st.sent = len(st.entries)
+	return b.String()
+}
+
+// writeHeader sets the timings so far as the Server-Timing header
+func (st *ServerTimings) writeHeader(h http.Header) {
+	if st == nil {
+		return
+	}
+
+	if v := st.flush(); v != "" {
+		h.Set("Server-Timing", v)
+	}
+}
+
+// writeTrailer sets the remaining timings as a Server-Timing trailer
+func (st *ServerTimings) writeTrailer(h http.Header) {
+	if st == nil {
+		return
+	}
+
+	if v := st.flush(); v != "" {
+		h.Set(http.TrailerPrefix+"Server-Timing", v)
+	}
+}
+
+type serverTimingsKey struct{}
+
+// ServerTimingsFromContext returns the timings of the request such that
+// handlers can add their own. It returns nil if the Codec was not configured
+// with the ServerTiming option, which is safe to use.
+func ServerTimingsFromContext(ctx context.Context) *ServerTimings {
+	st, _ := ctx.Value(serverTimingsKey{}).(*ServerTimings)
+	return st
+}
+
+// withServerTimings returns a shallow copy of the request with timings in its
+// context.
+func withServerTimings(r *http.Request) (*http.Request, *ServerTimings) {
+	st := &ServerTimings{}
+	return r.WithContext(context.WithValue(r.Context(), serverTimingsKey{}, st)), st
+}
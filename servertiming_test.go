@@ -0,0 +1,149 @@
+package ep
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/advanderveer/ep/epcoding"
+)
+
+// timingNames returns the metric names in a Server-Timing value
+func timingNames(v string) (names []string) {
+	for _, m := range regexp.MustCompile(`(?:^|, )([a-z]+);dur=[0-9]+\.[0-9]{3}`).FindAllStringSubmatch(v, -1) {
+		names = append(names, m[1])
+	}
+
+	return
+}
+
+func TestServerTiming(t *testing.T) {
+	type input struct{ Foo string }
+
+	for i, c := range []struct {
+		opts       []Option
+		handle     interface{}
+		expHeader  string
+		expTrailer string
+	}{
+		{
+			opts: []Option{ServerTiming(), ResponseHook(func(w http.ResponseWriter, r *http.Request, out interface{}) {})},
+			handle: func(ctx context.Context, in *input) *input {
+				ServerTimingsFromContext(ctx).Add("db", "query", time.Millisecond)
+				return in
+			},
+			expHeader: "negotiate,decode,db,call,encode,reshooks",
+		},
+		{
+			opts: []Option{ServerTiming(), ResponseHook(func(w http.ResponseWriter, r *http.Request, out interface{}) {
+				w.WriteHeader(http.StatusCreated)
+			})},
+			handle: func(in *input) *input {
+				return in
+			},
+			expHeader: "negotiate,decode,call,encode,reshooks",
+		},
+		{
+			opts: []Option{ServerTiming()},
+			handle: func(w ResponseWriter) {
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte("foo"))
+			},
+			expHeader:  "negotiate",
+			expTrailer: "call",
+		},
+		{
+			opts: []Option{ServerTiming()},
+			handle: func(w ResponseWriter, r *http.Request) {
+				ServerTimingsFromContext(r.Context()).Add("db", "", time.Millisecond)
+			},
+			expHeader: "negotiate,db,call",
+		},
+		{
+			opts: []Option{ServerTiming()},
+			handle: func(w ResponseWriter, r *http.Request) {
+				w.Render(struct{ Foo string }{"bar"})
+			},
+			expHeader:  "negotiate,encode",
+			expTrailer: "call",
+		},
+		{
+			opts: []Option{ServerTiming(), BufferOutputs()},
+			handle: func(in *input) *input {
+				return in
+			},
+			expHeader: "negotiate,decode,call,encode",
+		},
+		{
+			opts:      []Option{ServerTiming(), RequestHook(func(r *http.Request, in interface{}) error { return nil })},
+			handle:    func(in *input) {},
+			expHeader: "negotiate,reqhooks,decode,call",
+		},
+		{
+			handle: func(ctx context.Context) {
+				ServerTimingsFromContext(ctx).Add("db", "", time.Millisecond)
+				ServerTimingsFromContext(ctx).Start("db", "")()
+			},
+		},
+	} {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/", strings.NewReader(`{"Foo": "bar"}`))
+			r.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			New(append(c.opts,
+				RequestDecoding(epcoding.JSON{}),
+				ResponseEncoding(epcoding.JSON{}))...,
+			).Handle(c.handle).ServeHTTP(w, r)
+
+			res := w.Result()
+			if names := strings.Join(timingNames(res.Header.Get("Server-Timing")), ","); names != c.expHeader {
+				t.Fatalf("unexpected header timings, got: %v (%v)", names, res.Header.Get("Server-Timing"))
+			}
+
+			if names := strings.Join(timingNames(res.Trailer.Get("Server-Timing")), ","); names != c.expTrailer {
+				t.Fatalf("unexpected trailer timings, got: %v", names)
+			}
+		})
+	}
+}
+
+func TestServerTimingOverServer(t *testing.T) {
+	srv := httptest.NewServer(New(
+		ServerTiming(),
+		ResponseEncoding(epcoding.JSON{}),
+		ResponseHook(func(w http.ResponseWriter, r *http.Request, out interface{}) {
+			w.WriteHeader(http.StatusOK)
+		}),
+	).Handle(func() interface{} { return struct{ Foo string }{"bar"} }))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer resp.Body.Close()
+	if names := strings.Join(timingNames(resp.Header.Get("Server-Timing")), ","); names != "negotiate,call,encode,reshooks" {
+		t.Fatalf("unexpected header timings, got: %v", names)
+	}
+}
+
+func TestServerTimingsFormat(t *testing.T) {
+	st := &ServerTimings{}
+	st.Add("db", `fetch "ideas"`, 1500*time.Microsecond)
+	st.Add("db", "", time.Millisecond)
+	st.Add("cache", "", 0)
+
+	if v := st.flush(); v != `db;dur=2.500;desc="fetch \"ideas\"", cache;dur=0.000` {
+		t.Fatalf("unexpected format, got: %v", v)
+	}
+
+	if v := st.flush(); v != "" {
+		t.Fatalf("should not send timings twice, got: %v", v)
+	}
+}
@@ -0,0 +1,221 @@
+package ep
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// Tracer starts spans for the phases of handling a request. It is a minimal
+// interface such that any tracing backend can be adapted. Implementations
+// should create the span as a child of the TraceContext in 'ctx' (if any) and
+// return a context that carries the span's own TraceContext, such that it
+// propagates to child spans and outgoing requests (see InjectTraceContext).
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span represents a single operation in a trace
+type Span interface {
+	SetAttribute(key string, value interface{})
+	RecordError(err error)
+	End()
+}
+
+// Tracing option will cause the Codec to create spans with the tracer for
+// each request. A root span covers the whole request and child spans are
+// created for negotiation, bind, calling the handler, response hooks and
+// encoding. Incoming traceparent and tracestate headers are parsed such that
+// the root span continues the trace of the client.
+func Tracing(t Tracer) Option {
+	return tracing{t}
+}
+
+type tracing struct{ t Tracer }
+
+func (o tracing) apply(c *Codec) {
+	c.tracer = o.t
+}
+
+// TraceContext identifies a span in a distributed trace as described by the
+// W3C Trace Context specification.
+type TraceContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+	Flags   byte
+	State   string // vendor-specific tracestate, propagated as-is
+}
+
+// IsValid reports whether both the trace and span ID are non-zero
+func (tc TraceContext) IsValid() bool {
+	return tc.TraceID != [16]byte{} && tc.SpanID != [8]byte{}
+}
+
+// Sampled reports whether the sampled flag is set
+func (tc TraceContext) Sampled() bool { return tc.Flags&0x01 != 0 }
+
+// String formats the trace context as a (version 00) traceparent header value
+func (tc TraceContext) String() string {
+	return "00-" + hex.EncodeToString(tc.TraceID[:]) + "-" +
+		hex.EncodeToString(tc.SpanID[:]) + "-" + hex.EncodeToString([]byte{tc.Flags})
+}
+
+// Child returns the trace context for a new span that is a child of this one.
+// If the trace context is not valid it starts a new (sampled) trace.
+func (tc TraceContext) Child() TraceContext {
+	if tc.TraceID == [16]byte{} {
+		tc.TraceID, tc.Flags, tc.State = [16]byte{}, 0x01, ""
+		randomID(tc.TraceID[:])
+	}
+
+	randomID(tc.SpanID[:])
+	return tc
+}
+
+// randomID fills 'b' with a random non-zero ID
+func randomID(b []byte) {
+	for {
+		if _, err := rand.Read(b); err != nil {
+			panic("ep: failed to read random bytes: " + err.Error())
+		}
+
+		for _, c := range b {
+			if c != 0 {
+				return
+			}
+		}
+	}
+}
+
+// ParseTraceparent parses the traceparent header value 'v'. It returns false
+// if the value is not valid.
+func ParseTraceparent(v string) (tc TraceContext, ok bool) {
+	v = strings.TrimSpace(v)
+	if len(v) < 55 || v[2] != '-' || v[35] != '-' || v[52] != '-' {
+		return tc, false
+	}
+
+	var ver [1]byte
+	if !decodeLowerHex(ver[:], v[:2]) || ver[0] == 0xff {
+		return tc, false
+	}
+
+	// version 00 has an exact length, future versions may append fields
+	if (ver[0] == 0x00 && len(v) != 55) || (len(v) > 55 && v[55] != '-') {
+		return tc, false
+	}
+
+	var flags [1]byte
+	if !decodeLowerHex(tc.TraceID[:], v[3:35]) ||
+		!decodeLowerHex(tc.SpanID[:], v[36:52]) ||
+		!decodeLowerHex(flags[:], v[53:55]) {
+		return TraceContext{}, false
+	}
+
+	tc.Flags = flags[0]
+	if !tc.IsValid() {
+		return TraceContext{}, false
+	}
+
+	return tc, true
+}
+
+// decodeLowerHex decodes 's' into 'dst', upper case hex is not allowed
+func decodeLowerHex(dst []byte, s string) bool {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+
+	_, err := hex.Decode(dst, []byte(s))
+	return err == nil
+}
+
+// ExtractTraceContext reads the traceparent and tracestate headers. It returns
+// false if the request doesn't carry a valid trace context.
+func ExtractTraceContext(h http.Header) (tc TraceContext, ok bool) {
+	tc, ok = ParseTraceparent(h.Get("traceparent"))
+	if !ok {
+		return
+	}
+
+	tc.State = strings.Join(h.Values("tracestate"), ",")
+	return
+}
+
+// InjectTraceContext writes the traceparent and tracestate headers for the
+// trace context in 'ctx', for example to propagate the trace to an outgoing
+// request. It does nothing if the context carries no valid trace context.
+func InjectTraceContext(ctx context.Context, h http.Header) {
+	tc := TraceContextFromContext(ctx)
+	if !tc.IsValid() {
+		return
+	}
+
+	h.Set("traceparent", tc.String())
+	if tc.State != "" {
+		h.Set("tracestate", tc.State)
+	} else {
+		h.Del("tracestate")
+	}
+}
+
+type traceContextKey struct{}
+
+// ContextWithTraceContext returns a copy of the context that carries 'tc'
+func ContextWithTraceContext(ctx context.Context, tc TraceContext) context.Context {
+	return context.WithValue(ctx, traceContextKey{}, tc)
+}
+
+// TraceContextFromContext returns the trace context in 'ctx', it is the zero
+// (invalid) value if the context doesn't carry one.
+func TraceContextFromContext(ctx context.Context) TraceContext {
+	tc, _ := ctx.Value(traceContextKey{}).(TraceContext)
+	return tc
+}
+
+// nopSpan is used when the Codec is not configured with a tracer
+type nopSpan struct{}
+
+func (nopSpan) SetAttribute(key string, value interface{}) {}
+func (nopSpan) RecordError(err error)                      {}
+func (nopSpan) End()                                       {}
+
+// withRootSpan returns a shallow copy of the request with the root span for
+// the request started in its context.
+func withRootSpan(r *http.Request, t Tracer) (*http.Request, Span) {
+	ctx := r.Context()
+	if tc, ok := ExtractTraceContext(r.Header); ok {
+		ctx = ContextWithTraceContext(ctx, tc)
+	}
+
+	ctx, span := t.Start(ctx, "ep.request")
+	span.SetAttribute("http.method", r.Method)
+	span.SetAttribute("http.path", r.URL.Path)
+	return r.WithContext(ctx), span
+}
+
+// span starts a child span of the request's root span
+func (res *response) span(name string) Span {
+	if res.tracer == nil {
+		return nopSpan{}
+	}
+
+	_, span := res.tracer.Start(res.req.Context(), name)
+	return span
+}
+
+// spanRequest starts a child span of the request's root span and returns a
+// shallow copy of the request that carries it. The request is not copied if
+// there is no tracer.
+func (res *response) spanRequest(name string) (*http.Request, Span) {
+	if res.tracer == nil {
+		return res.req, nopSpan{}
+	}
+
+	ctx, span := res.tracer.Start(res.req.Context(), name)
+	return res.req.WithContext(ctx), span
+}
@@ -0,0 +1,75 @@
+package ep
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	for i, c := range []struct {
+		v      string
+		expOK  bool
+		expStr string
+	}{
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+		{" 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00 ", true, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00"},
+		{"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-foo", true, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-foo", false, ""},
+		{"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01foo", false, ""},
+		{"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false, ""},
+		{"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", false, ""},
+		{"00-00000000000000000000000000000000-00f067aa0ba902b7-01", false, ""},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", false, ""},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7", false, ""},
+		{"", false, ""},
+	} {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			tc, ok := ParseTraceparent(c.v)
+			if ok != c.expOK {
+				t.Fatalf("expected ok %v, got: %v", c.expOK, ok)
+			}
+
+			if ok && tc.String() != c.expStr {
+				t.Fatalf("expected %v, got: %v", c.expStr, tc.String())
+			}
+		})
+	}
+}
+
+func TestTraceContextChild(t *testing.T) {
+	root := TraceContext{}.Child()
+	if !root.IsValid() || !root.Sampled() {
+		t.Fatalf("should start a new sampled trace, got: %v", root)
+	}
+
+	root.State = "foo=bar"
+	child := root.Child()
+	if child.TraceID != root.TraceID || child.SpanID == root.SpanID || child.State != "foo=bar" {
+		t.Fatalf("unexpected child, got: %v", child)
+	}
+}
+
+func TestTracePropagation(t *testing.T) {
+	h := http.Header{}
+	h.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	h.Add("tracestate", "a=1")
+	h.Add("tracestate", "b=2")
+
+	tc, ok := ExtractTraceContext(h)
+	if !ok || tc.State != "a=1,b=2" {
+		t.Fatalf("unexpected trace context, got: %v %v", tc, ok)
+	}
+
+	out := http.Header{}
+	InjectTraceContext(context.Background(), out)
+	if len(out) != 0 {
+		t.Fatalf("should not inject without trace context, got: %v", out)
+	}
+
+	InjectTraceContext(ContextWithTraceContext(context.Background(), tc), out)
+	if out.Get("traceparent") != h.Get("traceparent") || out.Get("tracestate") != "a=1,b=2" {
+		t.Fatalf("unexpected injected headers, got: %v", out)
+	}
+}
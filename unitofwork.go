@@ -0,0 +1,112 @@
+package ep
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+)
+
+// Tx is a unit of work that is committed or rolled back depending on the
+// outcome of calling a handler, *sql.Tx implements it.
+type Tx interface {
+	Commit() error
+	Rollback() error
+}
+
+// UnitOfWork option begins a unit of work (e.g. a database transaction) before
+// every handler is called and puts it in the request context, where it can be
+// retrieved with TxFromContext or injected with a provider. If the handler
+// panics, returns an error or an output with a Status() of 400 or higher, the
+// unit of work is rolled back. Otherwise it is committed, before the header is
+// written, such that a failure to commit is rendered as an error instead.
+// Handlers that take a ResponseWriter and write the header themselves are
+// committed after the fact, a failure to commit can then only be logged.
+//
+// It is installed as call middleware so any CallMiddleware that is
+// configured before it runs outside of the unit of work.
+func UnitOfWork(begin func(r *http.Request) (Tx, error)) Option {
+	return CallMiddleware(func(next Call) Call {
+		return func(w ResponseWriter, r *http.Request, in interface{}) (outs []interface{}) {
+			const op Op = "ep.UnitOfWork"
+
+			tx, err := begin(r)
+			if err != nil {
+				if Kind(err) == OtherError {
+					return []interface{}{Err(op, "failed to begin", err, ServerError)}
+				}
+
+				return []interface{}{Err(op, "failed to begin", err)}
+			}
+
+			r = r.WithContext(context.WithValue(r.Context(), txKey{}, tx))
+			logs := LoggerFromContext(r.Context())
+
+			defer func() {
+				if v := recover(); v != nil {
+					if err := tx.Rollback(); err != nil {
+						logs.Error("failed to roll back unit of work", ErrorAttrs(err)...)
+					}
+
+					panic(v)
+				}
+			}()
+
+			outs = next(w, r, in)
+			if failedCall(w, outs) {
+				if err := tx.Rollback(); err != nil {
+					logs.Error("failed to roll back unit of work", ErrorAttrs(err)...)
+				}
+
+				return outs
+			}
+
+			if err := tx.Commit(); err != nil {
+				err = Err(op, "failed to commit", err, ServerError)
+				if res, ok := w.(*response); ok && res.wroteHeader {
+					logs.Error("failed to commit unit of work after the header was written", ErrorAttrs(err)...)
+					return outs
+				}
+
+				return []interface{}{err}
+			}
+
+			return outs
+		}
+	})
+}
+
+// failedCall determines if the outputs of a call, or the status that was
+// written by the handler itself, indicate that it failed.
+func failedCall(w ResponseWriter, outs []interface{}) bool {
+	if res, ok := w.(*response); ok && res.wroteHeader && res.status >= 400 {
+		return true
+	}
+
+	for _, out := range outs {
+		switch outt := out.(type) {
+		case nil:
+		case error:
+			return true
+		case interface{ Status() int }:
+			// as with render, nil pointers are not considered outputs
+			if rv := reflect.ValueOf(out); rv.Kind() == reflect.Ptr && rv.IsNil() {
+				continue
+			}
+
+			if outt.Status() >= 400 {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+type txKey struct{}
+
+// TxFromContext returns the unit of work of the request, or nil if the Codec
+// was not configured with the UnitOfWork option.
+func TxFromContext(ctx context.Context) Tx {
+	tx, _ := ctx.Value(txKey{}).(Tx)
+	return tx
+}
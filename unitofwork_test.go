@@ -0,0 +1,100 @@
+package ep
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/advanderveer/ep/epcoding"
+)
+
+type testUnitOfWork struct {
+	calls     *[]string
+	commitErr error
+}
+
+func (tx *testUnitOfWork) Commit() error {
+	*tx.calls = append(*tx.calls, "commit")
+	return tx.commitErr
+}
+
+func (tx *testUnitOfWork) Rollback() error {
+	*tx.calls = append(*tx.calls, "rollback")
+	return nil
+}
+
+func TestUnitOfWork(t *testing.T) {
+	var calls []string
+	begin := func(commitErr, beginErr error) Option {
+		return UnitOfWork(func(r *http.Request) (Tx, error) {
+			calls = append(calls, "begin")
+			return &testUnitOfWork{&calls, commitErr}, beginErr
+		})
+	}
+
+	for i, c := range []struct {
+		handler   interface{}
+		commitErr error
+		beginErr  error
+		expCalls  string
+		expCode   int
+		expBody   string
+	}{
+		{func(tx Tx) string {
+			calls = append(calls, "handler")
+			if tx == nil {
+				t.Fatal("should have provided the unit of work")
+			}
+
+			return "ok"
+		}, nil, nil, "begin,handler,commit", 200, `"ok"` + "\n"},
+		{func() (string, error) { return "", errors.New("foo") }, nil, nil, "begin,rollback", 400, ""},
+		{func() *statusOutput { return &statusOutput{404} }, nil, nil, "begin,rollback", 404, ""},
+		{func() *statusOutput { return &statusOutput{201} }, nil, nil, "begin,commit", 201, ""},
+		{func() string { panic("boom") }, nil, nil, "begin,rollback", 500, ""},
+		{func() string { return "ok" }, errors.New("conflict"), nil, "begin,commit", 500, ""},
+		{func() string {
+			calls = append(calls, "handler")
+			return "ok"
+		}, nil, Err("db", "unavailable", UnavailableError), "begin", 400, ""},
+		{func(w ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		}, nil, nil, "begin,rollback", 502, ""},
+		{func(w ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok"))
+		}, errors.New("conflict"), nil, "begin,commit", 200, "ok"},
+	} {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			calls = nil
+			h := New(
+				ResponseEncoding(epcoding.JSON{}),
+				testErrorHooks,
+				PhasedResponseHook(StatusPhase, func(w http.ResponseWriter, r *http.Request, out interface{}) {
+					if o, ok := out.(*statusOutput); ok {
+						w.WriteHeader(o.code)
+					}
+				}),
+				begin(c.commitErr, c.beginErr),
+				Provide(func(r *http.Request) (Tx, error) { return TxFromContext(r.Context()), nil }),
+			).Handle(c.handler)
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+			if strings.Join(calls, ",") != c.expCalls {
+				t.Fatalf("expected calls %v, got: %v", c.expCalls, calls)
+			}
+
+			if w.Code != c.expCode {
+				t.Fatalf("expected code %v, got: %v", c.expCode, w.Code)
+			}
+
+			if c.expBody != "" && w.Body.String() != c.expBody {
+				t.Fatalf("expected body %v, got: %v", c.expBody, w.Body.String())
+			}
+		})
+	}
+}